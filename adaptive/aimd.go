@@ -0,0 +1,47 @@
+package adaptive
+
+import (
+	"time"
+)
+
+// AIMD 加性增，乘性减
+// 调用成功且并发接近上限时上限加一，出现丢弃或超时后上限乘以 backoffRatio
+type AIMD struct {
+	limit        float64
+	minLimit     float64
+	maxLimit     float64
+	backoffRatio float64
+	timeout      time.Duration
+}
+
+// NewAIMD timeout 为 0 表示只以 didDrop 作为拥塞信号
+func NewAIMD(initialLimit, minLimit, maxLimit int64, backoffRatio float64, timeout time.Duration) *AIMD {
+	if backoffRatio <= 0 || backoffRatio >= 1 {
+		backoffRatio = 0.9
+	}
+	return &AIMD{
+		limit:        float64(initialLimit),
+		minLimit:     float64(minLimit),
+		maxLimit:     float64(maxLimit),
+		backoffRatio: backoffRatio,
+		timeout:      timeout,
+	}
+}
+
+func (a *AIMD) Update(rtt time.Duration, inflight int64, didDrop bool) int64 {
+	if didDrop || (a.timeout > 0 && rtt > a.timeout) {
+		a.limit = clamp(a.limit*a.backoffRatio, a.minLimit, a.maxLimit)
+	} else if float64(inflight)*2 >= a.limit {
+		// 并发不足上限一半时说明没有压力，不增加上限
+		a.limit = clamp(a.limit+1, a.minLimit, a.maxLimit)
+	}
+	return int64(a.limit)
+}
+
+func (a *AIMD) Limit() int64 {
+	return int64(a.limit)
+}
+
+func (a *AIMD) String() string {
+	return "aimd"
+}
@@ -0,0 +1,25 @@
+package adaptive
+
+import (
+	"time"
+)
+
+// Algorithm 并发上限调整算法
+// 每次调用结束后根据耗时与是否被丢弃计算新的上限，由 Limiter 串行调用
+type Algorithm interface {
+	// Update rtt 本次调用耗时，inflight 调用开始时的并发数，didDrop 调用是否超时或被下游丢弃
+	Update(rtt time.Duration, inflight int64, didDrop bool) int64
+	// Limit 当前上限
+	Limit() int64
+	String() string
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
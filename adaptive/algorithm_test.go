@@ -0,0 +1,106 @@
+package adaptive
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAIMD_Update(t *testing.T) {
+	ts := []struct {
+		name     string
+		rtt      time.Duration
+		inflight int64
+		didDrop  bool
+		n        int
+		expect   int64
+	}{
+		{name: "grow", rtt: time.Millisecond, inflight: 10, n: 5, expect: 15},
+		{name: "no pressure", rtt: time.Millisecond, inflight: 1, n: 5, expect: 10},
+		{name: "drop", rtt: time.Millisecond, inflight: 10, didDrop: true, n: 1, expect: 5},
+		{name: "timeout", rtt: time.Second, inflight: 10, n: 1, expect: 5},
+		{name: "clamp max", rtt: time.Millisecond, inflight: 100, n: 100, expect: 20},
+		{name: "clamp min", rtt: time.Millisecond, inflight: 10, didDrop: true, n: 10, expect: 2},
+	}
+
+	for _, s := range ts {
+		a := NewAIMD(10, 2, 20, 0.5, 100*time.Millisecond)
+		var limit int64
+		for i := 0; i < s.n; i++ {
+			limit = a.Update(s.rtt, s.inflight, s.didDrop)
+		}
+		if limit != s.expect || a.Limit() != s.expect {
+			t.Errorf("%s: expect limit %v, but %v", s.name, s.expect, limit)
+		}
+	}
+}
+
+func TestVegas_Update(t *testing.T) {
+	ts := []struct {
+		name    string
+		rtt     time.Duration
+		didDrop bool
+		n       int
+		expect  int64
+	}{
+		// 没有排队时每次增加 beta
+		{name: "grow", rtt: 10 * time.Millisecond, n: 1, expect: 16},
+		{name: "drop", rtt: 10 * time.Millisecond, didDrop: true, n: 1, expect: 9},
+		// 耗时翻倍说明排队过长
+		{name: "queueing", rtt: 100 * time.Millisecond, n: 1, expect: 9},
+		{name: "clamp max", rtt: 10 * time.Millisecond, n: 100, expect: 50},
+		{name: "clamp min", rtt: 10 * time.Millisecond, didDrop: true, n: 100, expect: 1},
+	}
+
+	for _, s := range ts {
+		v := NewVegas(10, 50)
+		// 第一次采样确定无负载耗时
+		v.Update(10*time.Millisecond, 10, false)
+		var limit int64
+		for i := 0; i < s.n; i++ {
+			limit = v.Update(s.rtt, 100, s.didDrop)
+		}
+		if limit != s.expect {
+			t.Errorf("%s: expect limit %v, but %v", s.name, s.expect, limit)
+		}
+	}
+}
+
+func TestGradient2_Update(t *testing.T) {
+	g := NewGradient2(100, 10, 200)
+	for i := 0; i < 10; i++ {
+		g.Update(10*time.Millisecond, 200, false)
+	}
+	grown := g.Limit()
+	if grown <= 100 {
+		t.Fatalf("expect limit grow, but %v", grown)
+	}
+
+	// 耗时突增，梯度小于 1，上限收缩
+	if limit := g.Update(100*time.Millisecond, 200, false); limit >= grown {
+		t.Errorf("expect limit shrink from %v, but %v", grown, limit)
+	}
+
+	// 没有压力时不调整
+	limit := g.Limit()
+	if g.Update(10*time.Millisecond, 1, false) != limit {
+		t.Errorf("expect limit unchanged %v", limit)
+	}
+
+	for i := 0; i < 1000; i++ {
+		g.Update(10*time.Millisecond, 1000, false)
+	}
+	if g.Limit() != 200 {
+		t.Errorf("expect limit clamp to 200, but %v", g.Limit())
+	}
+
+	g = NewGradient2(20, 10, 200)
+	for i := 0; i < 10; i++ {
+		g.Update(10*time.Millisecond, 200, false)
+	}
+	for i := 0; i < 50; i++ {
+		g.Update(time.Second, 200, false)
+	}
+	if g.Limit() != 10 {
+		t.Errorf("expect limit clamp to 10, but %v", g.Limit())
+	}
+}
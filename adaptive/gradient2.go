@@ -0,0 +1,94 @@
+package adaptive
+
+import (
+	"math"
+	"time"
+)
+
+// Gradient2 参考 Netflix concurrency-limits Gradient2Limit
+// 长期平均耗时与本次耗时的比值作为梯度：gradient = tolerance * longRtt / rtt
+// 梯度小于 1 说明在排队，上限按梯度收缩；否则上限增加 queueSize
+type Gradient2 struct {
+	limit     float64
+	minLimit  float64
+	maxLimit  float64
+	smoothing float64
+	tolerance float64
+	longRtt   *ewma
+}
+
+func NewGradient2(initialLimit, minLimit, maxLimit int64) *Gradient2 {
+	return &Gradient2{
+		limit:     float64(initialLimit),
+		minLimit:  float64(minLimit),
+		maxLimit:  float64(maxLimit),
+		smoothing: 0.2,
+		tolerance: 1.5,
+		longRtt:   newEWMA(600, 10),
+	}
+}
+
+func (g *Gradient2) Update(rtt time.Duration, inflight int64, didDrop bool) int64 {
+	if rtt <= 0 {
+		return int64(g.limit)
+	}
+
+	shortRtt := float64(rtt)
+	longRtt := g.longRtt.add(shortRtt)
+
+	// 下游恢复后长期耗时下降较慢，加速衰减
+	if longRtt/shortRtt > 2 {
+		g.longRtt.update(func(v float64) float64 {
+			return v * 0.95
+		})
+	}
+
+	// 没有压力时不调整
+	if float64(inflight) < g.limit/2 {
+		return int64(g.limit)
+	}
+
+	gradient := clamp(g.tolerance*longRtt/shortRtt, 0.5, 1.0)
+	queueSize := math.Sqrt(g.limit)
+	newLimit := g.limit*gradient + queueSize
+	newLimit = g.limit*(1-g.smoothing) + newLimit*g.smoothing
+	g.limit = clamp(newLimit, g.minLimit, g.maxLimit)
+	return int64(g.limit)
+}
+
+func (g *Gradient2) Limit() int64 {
+	return int64(g.limit)
+}
+
+func (g *Gradient2) String() string {
+	return "gradient2"
+}
+
+// ewma 指数加权平均，前 warmup 个采样使用算术平均
+type ewma struct {
+	factor float64
+	warmup int
+	count  int
+	value  float64
+}
+
+func newEWMA(window int, warmup int) *ewma {
+	return &ewma{
+		factor: 2.0 / float64(window+1),
+		warmup: warmup,
+	}
+}
+
+func (e *ewma) add(v float64) float64 {
+	if e.count < e.warmup {
+		e.count++
+		e.value += (v - e.value) / float64(e.count)
+	} else {
+		e.value = e.value*(1-e.factor) + v*e.factor
+	}
+	return e.value
+}
+
+func (e *ewma) update(fn func(v float64) float64) {
+	e.value = fn(e.value)
+}
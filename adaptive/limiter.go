@@ -0,0 +1,174 @@
+package adaptive
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lanceryou/defender"
+)
+
+var (
+	AdaptiveLimitErr = errors.New("adaptive concurrency limit exceeded")
+)
+
+// Limiter 自适应并发限制
+// 并发上限由 Algorithm 根据调用完成时的耗时动态调整
+type Limiter struct {
+	opt      Options
+	mu       sync.Mutex
+	alg      Algorithm
+	limit    int64
+	inflight int64
+}
+
+func NewLimiter(alg Algorithm, opts ...Option) *Limiter {
+	opt := Options{
+		priority: defaultPriority,
+		dropped:  isTimeout,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &Limiter{
+		opt:   opt,
+		alg:   alg,
+		limit: alg.Limit(),
+	}
+}
+
+// Limit 当前并发上限
+func (l *Limiter) Limit() int64 {
+	return atomic.LoadInt64(&l.limit)
+}
+
+// InFlight 当前正在执行的调用数
+func (l *Limiter) InFlight() int64 {
+	return atomic.LoadInt64(&l.inflight)
+}
+
+// Acquire 占用一个并发名额，返回调用开始时的并发数
+func (l *Limiter) Acquire() (int64, bool) {
+	for {
+		cur := atomic.LoadInt64(&l.inflight)
+		if cur >= l.Limit() {
+			return cur, false
+		}
+		if atomic.CompareAndSwapInt64(&l.inflight, cur, cur+1) {
+			return cur + 1, true
+		}
+	}
+}
+
+// Release 归还名额，不调整上限，用于调用被其他组件拒绝的场景
+func (l *Limiter) Release() {
+	atomic.AddInt64(&l.inflight, -1)
+}
+
+// OnSample 归还名额并用本次调用结果调整上限
+func (l *Limiter) OnSample(rtt time.Duration, inflight int64, err error) {
+	atomic.AddInt64(&l.inflight, -1)
+
+	// 在锁内发布，保证上限按 Update 的顺序生效
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit := l.alg.Update(rtt, inflight, err != nil && l.opt.dropped(err))
+	if limit < 1 {
+		limit = 1
+	}
+	atomic.StoreInt64(&l.limit, limit)
+}
+
+// Allow 占用名额执行 fn，超过上限返回 *defender.BlockError
+func (l *Limiter) Allow(fn func() error) error {
+	done, err := l.TryPass()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = fn()
+	done(err, time.Since(start))
+	return err
+}
+
+// TryPass 两阶段接口，完成回调与 circuitbreaker.CircuitBreaker.TryPass 一致
+// 放行时返回 done，调用结束后上报错误与耗时，归还名额并调整上限，done 只有第一次调用生效
+func (l *Limiter) TryPass() (done func(err error, rt time.Duration), err error) {
+	inflight, ok := l.Acquire()
+	if !ok {
+		return nil, l.blockError(l.opt.resource)
+	}
+
+	var once int32
+	return func(err error, rt time.Duration) {
+		if atomic.CompareAndSwapInt32(&once, 0, 1) {
+			l.OnSample(rt, inflight, err)
+		}
+	}, nil
+}
+
+// Check 实现 defender.Defender
+// 只判断当前并发是否已满，不占用名额
+func (l *Limiter) Check(resource string) error {
+	if !l.match(resource) {
+		return nil
+	}
+	if l.InFlight() >= l.Limit() {
+		return l.blockError(resource)
+	}
+	return nil
+}
+
+// CheckEntry 实现 defender.EntryChecker，占用名额直到 Exit
+func (l *Limiter) CheckEntry(e *defender.Entry) error {
+	if !l.match(e.Resource()) {
+		return nil
+	}
+	inflight, ok := l.Acquire()
+	if !ok {
+		return l.blockError(e.Resource())
+	}
+	e.SetValue(l, inflight)
+	return nil
+}
+
+// Exit 实现 defender.ExitHandler，用调用耗时与准入时的并发数调整上限
+func (l *Limiter) Exit(e *defender.Entry, err error) {
+	inflight, ok := e.Value(l).(int64)
+	if !ok {
+		return
+	}
+	if e.BlockError() != nil {
+		l.Release()
+		return
+	}
+	l.OnSample(e.RT(), inflight, err)
+}
+
+func (l *Limiter) String() string {
+	if l.opt.resource == "" {
+		return "adaptive:" + l.alg.String()
+	}
+	return "adaptive:" + l.alg.String() + ":" + l.opt.resource
+}
+
+// Priority 实现 defender.Prioritized
+func (l *Limiter) Priority() int {
+	return l.opt.priority
+}
+
+func (l *Limiter) match(resource string) bool {
+	return l.opt.resource == "" || l.opt.resource == resource
+}
+
+func (l *Limiter) blockError(resource string) error {
+	return &defender.BlockError{
+		Resource: resource,
+		Kind:     "adaptive",
+		RuleID:   l.alg.String(),
+		Err:      AdaptiveLimitErr,
+	}
+}
@@ -0,0 +1,76 @@
+package adaptive
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lanceryou/defender"
+)
+
+func TestLimiter_TryPass(t *testing.T) {
+	l := NewLimiter(NewAIMD(2, 1, 10, 0.5, 0))
+	done1, err := l.TryPass()
+	if err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+	done2, err := l.TryPass()
+	if err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+	if _, err = l.TryPass(); !errors.Is(err, AdaptiveLimitErr) {
+		t.Fatalf("expect limit err, but %v", err)
+	}
+
+	// 并发打满时成功调用增加上限
+	done1(nil, time.Millisecond)
+	done1(nil, time.Millisecond)
+	if l.Limit() != 3 || l.InFlight() != 1 {
+		t.Errorf("expect limit 3 inflight 1, but %v %v", l.Limit(), l.InFlight())
+	}
+	// 超时视为丢弃，上限收缩
+	done2(context.DeadlineExceeded, time.Millisecond)
+	if l.Limit() != 1 || l.InFlight() != 0 {
+		t.Errorf("expect limit 1 inflight 0, but %v %v", l.Limit(), l.InFlight())
+	}
+}
+
+func TestLimiter_Enter(t *testing.T) {
+	l := NewLimiter(NewAIMD(1, 1, 10, 0.5, 0), WithResource("res"))
+	g := defender.NewGroup()
+	g.Register(l)
+
+	e, err := g.Enter(context.Background(), "res")
+	if err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+	if _, err = g.Enter(context.Background(), "res"); !errors.Is(err, AdaptiveLimitErr) {
+		t.Fatalf("expect limit err, but %v", err)
+	}
+	e.Exit(nil)
+	if l.Limit() != 2 || l.InFlight() != 0 {
+		t.Errorf("expect limit 2 inflight 0, but %v %v", l.Limit(), l.InFlight())
+	}
+
+	// 被后续组件拒绝时只归还名额，不调整上限
+	g.AddDefender(blockDefender{}, 200)
+	if _, err = g.Enter(context.Background(), "res"); !errors.Is(err, errBlock) {
+		t.Fatalf("expect block, but %v", err)
+	}
+	if l.Limit() != 2 || l.InFlight() != 0 {
+		t.Errorf("expect limit 2 inflight 0, but %v %v", l.Limit(), l.InFlight())
+	}
+}
+
+var errBlock = errors.New("block")
+
+type blockDefender struct{}
+
+func (blockDefender) Check(resource string) error {
+	return errBlock
+}
+
+func (blockDefender) String() string {
+	return "block"
+}
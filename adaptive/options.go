@@ -0,0 +1,42 @@
+package adaptive
+
+import (
+	"context"
+	"errors"
+)
+
+// 与 concurrency 同档
+const defaultPriority = 100
+
+type Options struct {
+	resource string
+	priority int
+	dropped  func(err error) bool
+}
+
+type Option func(*Options)
+
+// WithResource 限制的资源名，为空表示对所有资源生效
+func WithResource(resource string) Option {
+	return func(o *Options) {
+		o.resource = resource
+	}
+}
+
+// WithPriority 在治理链中的优先级
+func WithPriority(priority int) Option {
+	return func(o *Options) {
+		o.priority = priority
+	}
+}
+
+// WithDropClassifier 判断调用是否被丢弃（拥塞信号），默认超时视为丢弃
+func WithDropClassifier(fn func(err error) bool) Option {
+	return func(o *Options) {
+		o.dropped = fn
+	}
+}
+
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
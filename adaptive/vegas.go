@@ -0,0 +1,80 @@
+package adaptive
+
+import (
+	"math"
+	"time"
+)
+
+// Vegas 参考 TCP Vegas
+// 以最小耗时作为无负载耗时 rttNoLoad，估算排队长度 queue = limit * (1 - rttNoLoad/rtt)
+// 排队少于 alpha 增加上限，多于 beta 减少上限
+type Vegas struct {
+	limit     float64
+	maxLimit  float64
+	smoothing float64
+	rttNoLoad time.Duration
+	// 每 probeInterval 次采样重置一次 rttNoLoad，避免下游整体变慢后一直按旧的最小值估算
+	probeInterval int64
+	samples       int64
+}
+
+func NewVegas(initialLimit, maxLimit int64) *Vegas {
+	return &Vegas{
+		limit:         float64(initialLimit),
+		maxLimit:      float64(maxLimit),
+		smoothing:     1.0,
+		probeInterval: 1000,
+	}
+}
+
+func (v *Vegas) Update(rtt time.Duration, inflight int64, didDrop bool) int64 {
+	if rtt <= 0 {
+		return int64(v.limit)
+	}
+
+	v.samples++
+	if v.samples >= v.probeInterval {
+		v.samples = 0
+		v.rttNoLoad = rtt
+		return int64(v.limit)
+	}
+	if v.rttNoLoad == 0 || rtt < v.rttNoLoad {
+		v.rttNoLoad = rtt
+		return int64(v.limit)
+	}
+
+	limit := v.limit
+	log := math.Max(1, math.Log10(limit))
+	newLimit := limit
+	if didDrop {
+		newLimit = limit - log
+	} else if float64(inflight)*2 < limit {
+		return int64(v.limit)
+	} else {
+		queueSize := math.Ceil(limit * (1 - float64(v.rttNoLoad)/float64(rtt)))
+		alpha := 3 * log
+		beta := 6 * log
+		switch {
+		case queueSize <= log:
+			newLimit = limit + beta
+		case queueSize < alpha:
+			newLimit = limit + log
+		case queueSize > beta:
+			newLimit = limit - log
+		default:
+			return int64(v.limit)
+		}
+	}
+
+	newLimit = clamp(newLimit, 1, v.maxLimit)
+	v.limit = (1-v.smoothing)*limit + v.smoothing*newLimit
+	return int64(v.limit)
+}
+
+func (v *Vegas) Limit() int64 {
+	return int64(v.limit)
+}
+
+func (v *Vegas) String() string {
+	return "vegas"
+}
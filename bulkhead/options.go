@@ -0,0 +1,70 @@
+package bulkhead
+
+import (
+	"time"
+
+	"github.com/lanceryou/defender/circuitbreaker"
+)
+
+// 隔离占用资源，排在限流与熔断之后，避免被拒绝的请求占用名额
+const defaultPriority = 2000
+
+type Options struct {
+	resource string
+	priority int
+	maxWait  time.Duration
+
+	rejectPolicy   RejectPolicy
+	codelTarget    time.Duration
+	codelInterval  time.Duration
+	breaker        *circuitbreaker.CircuitBreaker
+	countRejection bool
+}
+
+type Option func(*Options)
+
+// WithResource 隔离的资源名，为空表示对所有资源生效
+func WithResource(resource string) Option {
+	return func(o *Options) {
+		o.resource = resource
+	}
+}
+
+// WithPriority 在治理链中的优先级
+func WithPriority(priority int) Option {
+	return func(o *Options) {
+		o.priority = priority
+	}
+}
+
+// WithMaxWait 名额不足时的最长等待时间，默认 0 不等待
+func WithMaxWait(maxWait time.Duration) Option {
+	return func(o *Options) {
+		o.maxWait = maxWait
+	}
+}
+
+// WithRejectPolicy 协程池队列满时的拒绝策略，默认 Reject
+func WithRejectPolicy(policy RejectPolicy) Option {
+	return func(o *Options) {
+		o.rejectPolicy = policy
+	}
+}
+
+// WithCoDel 协程池队列使用 CoDel 管理排队时长
+// 排队时长持续超过 target 时切换为后进先出，并丢弃排队超过 2*target 的任务
+func WithCoDel(target, interval time.Duration) Option {
+	return func(o *Options) {
+		o.codelTarget = target
+		o.codelInterval = interval
+	}
+}
+
+// WithCircuitBreaker 协程池任务经过熔断执行
+// countRejection 为 true 时协程池的拒绝也计入熔断失败
+func WithCircuitBreaker(cb *circuitbreaker.CircuitBreaker, countRejection bool) Option {
+	return func(o *Options) {
+		o.breaker = cb
+		o.countRejection = countRejection
+	}
+}
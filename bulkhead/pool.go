@@ -0,0 +1,272 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/pkg/codel"
+)
+
+var (
+	PoolClosedErr = errors.New("bulkhead pool closed")
+	TaskPanicErr  = errors.New("bulkhead task panic")
+)
+
+// RejectPolicy 队列满时的拒绝策略
+type RejectPolicy int32
+
+const (
+	// Reject 直接拒绝新任务
+	Reject RejectPolicy = iota
+	// CallerRuns 在调用方协程执行新任务
+	CallerRuns
+	// DropOldest 丢弃队列中等待最久的任务，新任务入队
+	DropOldest
+)
+
+func (p RejectPolicy) String() string {
+	switch p {
+	case Reject:
+		return "Reject"
+	case CallerRuns:
+		return "CallerRuns"
+	case DropOldest:
+		return "DropOldest"
+	default:
+		return fmt.Sprintf("RejectPolicy(%d)", int32(p))
+	}
+}
+
+const (
+	taskPending int32 = iota
+	taskRunning
+	taskCanceled
+)
+
+type task struct {
+	fn    func() error
+	done  chan error
+	state int32
+}
+
+// 任务结束，done 有缓冲，不会阻塞
+func (t *task) finish(err error) {
+	t.done <- err
+}
+
+// Pool 协程池隔离（线程池隔离）
+// 固定数量的 worker 从有界队列取任务执行，队列满时按 RejectPolicy 处理
+type Pool struct {
+	opt       Options
+	workers   int
+	queueSize int
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  *codel.Queue
+	idle   int // 等待任务的 worker 数
+	closed bool
+	wg     sync.WaitGroup
+
+	active int64
+}
+
+func NewPool(workers int, queueSize int, opts ...Option) *Pool {
+	if workers <= 0 || queueSize < 0 {
+		panic(fmt.Errorf("bulkhead: invalid pool workers %v queue size %v", workers, queueSize))
+	}
+	opt := Options{
+		priority: defaultPriority,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	var cd *codel.CoDel
+	if opt.codelTarget > 0 {
+		cd = codel.NewCoDel(opt.codelTarget, opt.codelInterval)
+	}
+	p := &Pool{
+		opt:       opt,
+		workers:   workers,
+		queueSize: queueSize,
+		queue:     codel.NewQueue(cd),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+// QueueDepth 等待执行的任务数
+func (p *Pool) QueueDepth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queue.Len()
+}
+
+// Active 正在执行的任务数
+func (p *Pool) Active() int64 {
+	return atomic.LoadInt64(&p.active)
+}
+
+// Allow 提交 fn 并等待执行结果
+func (p *Pool) Allow(fn func() error) error {
+	return p.Execute(context.Background(), fn)
+}
+
+// Execute 提交 fn 并等待执行结果
+// 排队期间 ctx 取消时任务不再执行，执行中的任务不会被中断
+// fn panic 时返回包装了 TaskPanicErr 的错误
+func (p *Pool) Execute(ctx context.Context, fn func() error) error {
+	t := &task{
+		fn:   fn,
+		done: make(chan error, 1),
+	}
+	if err := p.submit(t); err != nil {
+		p.onReject(err)
+		return err
+	}
+
+	select {
+	case err := <-t.done:
+		return err
+	case <-ctx.Done():
+		if atomic.CompareAndSwapInt32(&t.state, taskPending, taskCanceled) {
+			// 移出队列，不再占用队列容量
+			p.mu.Lock()
+			p.queue.Remove(t)
+			p.mu.Unlock()
+			return ctx.Err()
+		}
+		// 已经开始执行，等待结果
+		return <-t.done
+	}
+}
+
+// Close 不再接收任务，等待队列中的任务执行完
+func (p *Pool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *Pool) submit(t *task) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return PoolClosedErr
+	}
+	// 空闲 worker 可以直接接手，不占用队列容量
+	if p.queue.Len() < p.queueSize+p.idle {
+		p.queue.Push(t)
+		p.cond.Signal()
+		p.mu.Unlock()
+		return nil
+	}
+
+	switch p.opt.rejectPolicy {
+	case CallerRuns:
+		p.mu.Unlock()
+		p.run(t)
+		return nil
+	case DropOldest:
+		oldest, ok := p.queue.PopOldest()
+		if !ok {
+			break
+		}
+		p.queue.Push(t)
+		p.cond.Signal()
+		p.mu.Unlock()
+		p.drop(oldest.(*task))
+		return nil
+	}
+	p.mu.Unlock()
+	return p.blockError()
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		for p.queue.Len() == 0 && !p.closed {
+			p.idle++
+			p.cond.Wait()
+			p.idle--
+		}
+		if p.queue.Len() == 0 {
+			p.mu.Unlock()
+			return
+		}
+		v, dropped, ok := p.queue.Pop()
+		p.mu.Unlock()
+
+		// 排队过久被 CoDel 丢弃的任务
+		for _, d := range dropped {
+			p.drop(d.(*task))
+		}
+		if ok {
+			p.run(v.(*task))
+		}
+	}
+}
+
+// drop 拒绝排队中的任务
+func (p *Pool) drop(t *task) {
+	if atomic.CompareAndSwapInt32(&t.state, taskPending, taskCanceled) {
+		err := p.blockError()
+		p.onReject(err)
+		t.finish(err)
+	}
+}
+
+func (p *Pool) run(t *task) {
+	if !atomic.CompareAndSwapInt32(&t.state, taskPending, taskRunning) {
+		return
+	}
+	atomic.AddInt64(&p.active, 1)
+	defer atomic.AddInt64(&p.active, -1)
+
+	if p.opt.breaker != nil {
+		t.finish(p.opt.breaker.Allow(t.call))
+		return
+	}
+	t.finish(t.call())
+}
+
+// call 执行 fn，panic 转成错误返回给调用方，worker 继续工作
+func (t *task) call() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", TaskPanicErr, r)
+		}
+	}()
+	return t.fn()
+}
+
+// onReject 按配置把拒绝计入熔断失败
+func (p *Pool) onReject(err error) {
+	if p.opt.breaker == nil || !p.opt.countRejection {
+		return
+	}
+	p.opt.breaker.Allow(func() error {
+		return err
+	})
+}
+
+func (p *Pool) blockError() error {
+	return &defender.BlockError{
+		Resource: p.opt.resource,
+		Kind:     "bulkhead",
+		RuleID:   "pool",
+		Err:      BulkheadFullErr,
+	}
+}
@@ -0,0 +1,173 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lanceryou/defender"
+)
+
+func TestPool_RejectPolicy(t *testing.T) {
+	ts := []struct {
+		policy RejectPolicy
+		// 第二个（排队）与第三个（队列满）任务的结果
+		queued   error
+		overflow error
+	}{
+		{policy: Reject, overflow: BulkheadFullErr},
+		{policy: CallerRuns},
+		{policy: DropOldest, queued: BulkheadFullErr},
+	}
+
+	for _, s := range ts {
+		p := NewPool(1, 1, WithRejectPolicy(s.policy))
+		block := make(chan struct{})
+		running := make(chan struct{})
+		go p.Allow(func() error {
+			close(running)
+			<-block
+			return nil
+		})
+		<-running
+
+		queued := make(chan error, 1)
+		go func() {
+			queued <- p.Allow(func() error {
+				return nil
+			})
+		}()
+		for p.QueueDepth() != 1 {
+			time.Sleep(time.Millisecond)
+		}
+		if p.Active() != 1 {
+			t.Errorf("expect active 1, but %v", p.Active())
+		}
+
+		overflow := make(chan error, 1)
+		go func() {
+			overflow <- p.Allow(func() error {
+				return nil
+			})
+		}()
+		if s.policy == DropOldest {
+			if err := <-queued; !errors.Is(err, s.queued) || !defender.IsBlocked(err) {
+				t.Errorf("%v: expect queued err %v, but %v", s.policy, s.queued, err)
+			}
+		}
+		if s.policy != Reject {
+			close(block)
+		}
+		if err := <-overflow; !errors.Is(err, s.overflow) {
+			t.Errorf("%v: expect overflow err %v, but %v", s.policy, s.overflow, err)
+		}
+		if s.policy == Reject {
+			close(block)
+		}
+		if s.policy != DropOldest {
+			if err := <-queued; err != s.queued {
+				t.Errorf("%v: expect queued err %v, but %v", s.policy, s.queued, err)
+			}
+		}
+		p.Close()
+	}
+}
+
+func TestPool_CancelQueued(t *testing.T) {
+	p := NewPool(1, 1)
+	defer p.Close()
+	block := make(chan struct{})
+	running := make(chan struct{})
+	go p.Allow(func() error {
+		close(running)
+		<-block
+		return nil
+	})
+	<-running
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := make(chan error, 1)
+	go func() {
+		queued <- p.Execute(ctx, func() error {
+			t.Errorf("canceled task should not run")
+			return nil
+		})
+	}()
+	for p.QueueDepth() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-queued; !errors.Is(err, context.Canceled) {
+		t.Errorf("expect canceled, but %v", err)
+	}
+	// 取消的任务不再占用队列容量
+	if p.QueueDepth() != 0 {
+		t.Errorf("expect queue depth 0, but %v", p.QueueDepth())
+	}
+	close(block)
+}
+
+func TestPool_CoDel(t *testing.T) {
+	p := NewPool(1, 100, WithCoDel(time.Millisecond, 5*time.Millisecond))
+	defer p.Close()
+	block := make(chan struct{})
+	running := make(chan struct{})
+	go p.Allow(func() error {
+		close(running)
+		<-block
+		return nil
+	})
+	<-running
+
+	// 每个任务执行 3ms，排队时长持续超过 target，排队过久的任务被丢弃
+	results := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		go func() {
+			results <- p.Allow(func() error {
+				time.Sleep(3 * time.Millisecond)
+				return nil
+			})
+		}()
+	}
+	for p.QueueDepth() != 20 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(block)
+
+	var ran, dropped int
+	for i := 0; i < 20; i++ {
+		err := <-results
+		switch {
+		case err == nil:
+			ran++
+		case errors.Is(err, BulkheadFullErr):
+			dropped++
+		default:
+			t.Errorf("unexpect err %v", err)
+		}
+	}
+	if ran == 0 || dropped == 0 {
+		t.Errorf("expect some tasks dropped, but ran %v dropped %v", ran, dropped)
+	}
+}
+
+func TestPool_Panic(t *testing.T) {
+	p := NewPool(1, 1)
+	defer p.Close()
+
+	err := p.Allow(func() error {
+		panic("boom")
+	})
+	if !errors.Is(err, TaskPanicErr) {
+		t.Errorf("expect err %v, but %v", TaskPanicErr, err)
+	}
+	if n := p.Active(); n != 0 {
+		t.Errorf("expect active 0, but %v", n)
+	}
+	// worker 仍然可用
+	if err := p.Allow(func() error { return nil }); err != nil {
+		t.Errorf("expect pass, but %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/concurrency"
+)
+
+var (
+	BulkheadFullErr = errors.New("bulkhead full")
+)
+
+// Bulkhead 信号量隔离（资源隔离）
+// 限制单个依赖能占用的并发数，慢依赖不会耗尽整个进程的协程与连接
+// 名额计数复用 concurrency.Limiter，在其之上增加名额不足时的有限等待
+type Bulkhead struct {
+	opt     Options
+	limiter *concurrency.Limiter
+
+	mu       sync.Mutex
+	waiters  int
+	released chan struct{} // 有名额归还时关闭，唤醒全部等待者
+}
+
+func NewBulkhead(maxConcurrent int, opts ...Option) *Bulkhead {
+	if maxConcurrent <= 0 {
+		panic(fmt.Errorf("bulkhead: maxConcurrent %v must be positive", maxConcurrent))
+	}
+	opt := Options{
+		priority: defaultPriority,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &Bulkhead{
+		opt:      opt,
+		limiter:  concurrency.NewLimiter(int64(maxConcurrent), concurrency.WithResource(opt.resource)),
+		released: make(chan struct{}),
+	}
+}
+
+// Acquire 获取名额，最多等待 maxWait
+// 超时返回 *defender.BlockError，ctx 取消时立即返回 ctx.Err()
+// 成功后必须调用 Release
+func (b *Bulkhead) Acquire(ctx context.Context) error {
+	return b.acquire(ctx, b.opt.resource)
+}
+
+func (b *Bulkhead) acquire(ctx context.Context, resource string) error {
+	if b.limiter.Acquire() {
+		return nil
+	}
+	if b.opt.maxWait <= 0 {
+		return b.blockError(resource)
+	}
+
+	t := time.NewTimer(b.opt.maxWait)
+	defer t.Stop()
+	b.mu.Lock()
+	b.waiters++
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.waiters--
+		b.mu.Unlock()
+	}()
+	for {
+		// 先取通知再尝试，避免错过两者之间的归还
+		b.mu.Lock()
+		released := b.released
+		b.mu.Unlock()
+		if b.limiter.Acquire() {
+			return nil
+		}
+		select {
+		case <-released:
+		case <-t.C:
+			return b.blockError(resource)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release 归还名额
+func (b *Bulkhead) Release() {
+	b.limiter.Release()
+	b.mu.Lock()
+	if b.waiters > 0 {
+		close(b.released)
+		b.released = make(chan struct{})
+	}
+	b.mu.Unlock()
+}
+
+// Do 占用名额执行 fn
+func (b *Bulkhead) Do(ctx context.Context, fn func() error) error {
+	if err := b.Acquire(ctx); err != nil {
+		return err
+	}
+	defer b.Release()
+	return fn()
+}
+
+// InUse 已占用的名额
+func (b *Bulkhead) InUse() int {
+	return int(b.limiter.InFlight())
+}
+
+// Available 剩余名额
+func (b *Bulkhead) Available() int {
+	return int(b.limiter.Max() - b.limiter.InFlight())
+}
+
+// Check 实现 defender.Defender，只判断是否还有名额
+func (b *Bulkhead) Check(resource string) error {
+	if !b.match(resource) {
+		return nil
+	}
+	if b.Available() == 0 {
+		return b.blockError(resource)
+	}
+	return nil
+}
+
+// CheckEntry 实现 defender.EntryChecker，占用名额直到 Exit
+func (b *Bulkhead) CheckEntry(e *defender.Entry) error {
+	if !b.match(e.Resource()) {
+		return nil
+	}
+	return b.acquire(e.Context(), e.Resource())
+}
+
+// Exit 实现 defender.ExitHandler，归还名额
+func (b *Bulkhead) Exit(e *defender.Entry, err error) {
+	if b.match(e.Resource()) {
+		b.Release()
+	}
+}
+
+func (b *Bulkhead) String() string {
+	if b.opt.resource == "" {
+		return "bulkhead"
+	}
+	return "bulkhead:" + b.opt.resource
+}
+
+// Priority 实现 defender.Prioritized
+func (b *Bulkhead) Priority() int {
+	return b.opt.priority
+}
+
+func (b *Bulkhead) match(resource string) bool {
+	return b.opt.resource == "" || b.opt.resource == resource
+}
+
+func (b *Bulkhead) blockError(resource string) error {
+	return &defender.BlockError{
+		Resource: resource,
+		Kind:     "bulkhead",
+		Err:      BulkheadFullErr,
+	}
+}
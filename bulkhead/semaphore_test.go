@@ -0,0 +1,83 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lanceryou/defender"
+)
+
+func TestBulkhead_Acquire(t *testing.T) {
+	b := NewBulkhead(1)
+	if err := b.Acquire(context.Background()); err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+	if err := b.Acquire(context.Background()); !errors.Is(err, BulkheadFullErr) {
+		t.Errorf("expect full, but %v", err)
+	}
+	if b.InUse() != 1 || b.Available() != 0 {
+		t.Errorf("expect in use 1, but %v %v", b.InUse(), b.Available())
+	}
+	b.Release()
+	if b.Available() != 1 {
+		t.Errorf("expect available 1, but %v", b.Available())
+	}
+}
+
+func TestBulkhead_Wait(t *testing.T) {
+	b := NewBulkhead(1, WithMaxWait(time.Second))
+	if err := b.Acquire(context.Background()); err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+
+	// 等待期间归还的名额可以被等待者拿到
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			err := b.Acquire(context.Background())
+			if err == nil {
+				b.Release()
+			}
+			done <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	b.Release()
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("expect pass after release, but %v", err)
+		}
+	}
+
+	b = NewBulkhead(1, WithMaxWait(10*time.Millisecond))
+	b.Acquire(context.Background())
+	if err := b.Acquire(context.Background()); !errors.Is(err, BulkheadFullErr) {
+		t.Errorf("expect full after max wait, but %v", err)
+	}
+}
+
+func TestBulkhead_Enter(t *testing.T) {
+	b := NewBulkhead(1, WithResource("res"), WithMaxWait(time.Second))
+	g := defender.NewGroup()
+	g.Register(b)
+
+	e, err := g.Enter(context.Background(), "res")
+	if err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+
+	// 调用方取消等待不是拒绝
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = g.Enter(ctx, "res")
+	if !errors.Is(err, context.DeadlineExceeded) || defender.IsBlocked(err) {
+		t.Errorf("expect deadline exceeded, but %v", err)
+	}
+
+	e.Exit(nil)
+	if b.InUse() != 0 {
+		t.Errorf("expect in use 0, but %v", b.InUse())
+	}
+}
@@ -0,0 +1,169 @@
+package defender
+
+// DefaultPriority 默认优先级，数值越小越先执行
+const DefaultPriority = 1000
+
+// Prioritized 治理组件自定义优先级
+// 开销小、不占用资源的检查排在前面，尽早拒绝；占用资源的隔离排在最后，避免被拒绝的请求占用名额
+// 内置组件的默认优先级：
+//
+//	40    priority        按请求优先级丢弃
+//	50    system          系统保护
+//	100   ratelimit，flow，concurrency，adaptive
+//	150   hotspot
+//	1000  circuitbreaker  未实现 Prioritized，使用 DefaultPriority
+//	2000  bulkhead
+type Prioritized interface {
+	Priority() int
+}
+
+func priorityOf(d Defender) int {
+	if p, ok := d.(Prioritized); ok {
+		return p.Priority()
+	}
+	return DefaultPriority
+}
+
+type slot struct {
+	defender Defender
+	priority int
+}
+
+// Chain 有序的治理链
+// 按优先级升序执行，优先级相同按加入顺序，同名组件只保留一个
+type Chain struct {
+	slots []slot
+}
+
+func NewChain() *Chain {
+	return &Chain{}
+}
+
+// Add 按优先级加入，同名组件会被替换
+func (c *Chain) Add(d Defender, priority int) {
+	c.Remove(d.String())
+	idx := len(c.slots)
+	for i, s := range c.slots {
+		if s.priority > priority {
+			idx = i
+			break
+		}
+	}
+	c.insert(idx, slot{defender: d, priority: priority})
+}
+
+// InsertBefore 插入到 name 之前，继承 name 的优先级
+// name 不存在返回 false
+func (c *Chain) InsertBefore(name string, d Defender) bool {
+	return c.insertAt(name, d, 0)
+}
+
+// InsertAfter 插入到 name 之后，继承 name 的优先级
+// name 不存在返回 false
+func (c *Chain) InsertAfter(name string, d Defender) bool {
+	return c.insertAt(name, d, 1)
+}
+
+// Remove 移除组件
+func (c *Chain) Remove(name string) bool {
+	idx := c.index(name)
+	if idx < 0 {
+		return false
+	}
+	c.slots = append(c.slots[:idx], c.slots[idx+1:]...)
+	return true
+}
+
+// Get 按名字查找组件
+func (c *Chain) Get(name string) Defender {
+	idx := c.index(name)
+	if idx < 0 {
+		return nil
+	}
+	return c.slots[idx].defender
+}
+
+// Defenders 按执行顺序返回
+func (c *Chain) Defenders() []Defender {
+	ds := make([]Defender, len(c.slots))
+	for i, s := range c.slots {
+		ds[i] = s.defender
+	}
+	return ds
+}
+
+// Len 组件数量
+func (c *Chain) Len() int {
+	return len(c.slots)
+}
+
+// Check 依次检测，返回第一个拒绝的 BlockError
+func (c *Chain) Check(resource string) error {
+	for _, s := range c.slots {
+		if err := s.defender.Check(resource); err != nil {
+			return toBlockError(err, resource, s.defender)
+		}
+	}
+	return nil
+}
+
+// CheckWithArgs 依次检测，实现 ArgsChecker 的组件可以看到调用参数
+func (c *Chain) CheckWithArgs(resource string, args ...interface{}) error {
+	for _, s := range c.slots {
+		var err error
+		if ac, ok := s.defender.(ArgsChecker); ok {
+			err = ac.CheckArgs(resource, args)
+		} else {
+			err = s.defender.Check(resource)
+		}
+		if err != nil {
+			return toBlockError(err, resource, s.defender)
+		}
+	}
+	return nil
+}
+
+// Enter 依次准入，拒绝时通知已经通过的组件
+func (c *Chain) Enter(e *Entry) error {
+	for _, s := range c.slots {
+		if err := e.check(s.defender); err != nil {
+			e.block(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Chain) clone() *Chain {
+	slots := make([]slot, len(c.slots))
+	copy(slots, c.slots)
+	return &Chain{slots: slots}
+}
+
+func (c *Chain) insertAt(name string, d Defender, offset int) bool {
+	if d.String() == name {
+		return c.index(name) >= 0
+	}
+	if c.index(name) < 0 {
+		return false
+	}
+	c.Remove(d.String())
+	idx := c.index(name)
+	c.insert(idx+offset, slot{defender: d, priority: c.slots[idx].priority})
+	return true
+}
+
+func (c *Chain) insert(idx int, s slot) {
+	c.slots = append(c.slots, slot{})
+	copy(c.slots[idx+1:], c.slots[idx:])
+	c.slots[idx] = s
+}
+
+func (c *Chain) index(name string) int {
+	for i, s := range c.slots {
+		if s.defender.String() == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,138 @@
+package defender
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testDefender struct {
+	name string
+	err  error
+	log  *[]string
+}
+
+func (d *testDefender) Check(resource string) error {
+	*d.log = append(*d.log, d.name)
+	return d.err
+}
+
+func (d *testDefender) String() string {
+	return d.name
+}
+
+func TestChain_Order(t *testing.T) {
+	var log []string
+	c := NewChain()
+	c.Add(&testDefender{name: "cb", log: &log}, 300)
+	c.Add(&testDefender{name: "flow", log: &log}, 100)
+	c.Add(&testDefender{name: "system", log: &log}, 100)
+	c.Add(&testDefender{name: "isolation", log: &log}, 200)
+	c.InsertBefore("flow", &testDefender{name: "auth", log: &log})
+	c.InsertAfter("cb", &testDefender{name: "log", log: &log})
+
+	for i := 0; i < 3; i++ {
+		log = log[:0]
+		if err := c.Check("res"); err != nil {
+			t.Fatalf("unexpect err %v", err)
+		}
+		got := strings.Join(log, ",")
+		expect := "auth,flow,system,isolation,cb,log"
+		if got != expect {
+			t.Errorf("expect order %v, but %v", expect, got)
+		}
+	}
+}
+
+func TestChain_Replace(t *testing.T) {
+	var log []string
+	errBlock := errors.New("block")
+	c := NewChain()
+	c.Add(&testDefender{name: "a", log: &log}, 100)
+	c.Add(&testDefender{name: "b", log: &log}, 200)
+	c.Add(&testDefender{name: "a", err: errBlock, log: &log}, 300)
+
+	if c.Len() != 2 {
+		t.Fatalf("expect len 2, but %v", c.Len())
+	}
+	err := c.Check("res")
+	if !errors.Is(err, errBlock) || !IsBlocked(err) {
+		t.Errorf("expect err %v, but %v", errBlock, err)
+	}
+	if be, ok := AsBlockError(err); !ok || be.Resource != "res" || be.Kind != "a" {
+		t.Errorf("unexpect block error %#v", be)
+	}
+	if got := strings.Join(log, ","); got != "b,a" {
+		t.Errorf("expect order b,a, but %v", got)
+	}
+
+	if c.InsertAfter("none", &testDefender{name: "c", log: &log}) {
+		t.Errorf("insert after missing defender should fail")
+	}
+	if !c.Remove("a") || c.Remove("a") {
+		t.Errorf("remove should succeed only once")
+	}
+}
+
+func TestGroup_Concurrent(t *testing.T) {
+	g := NewGroup()
+	g.Register(&nopDefender{name: "a"})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			g.Register(&nopDefender{name: "a"})
+			g.Replace(&nopDefender{name: "a"}, &nopDefender{name: "b"})
+			g.Unregister("b")
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		if err := g.Check("res"); err != nil {
+			t.Fatalf("unexpect err %v", err)
+		}
+		if n := len(g.List()); n < 1 || n > 2 {
+			t.Fatalf("defender a should always be registered, got %v", n)
+		}
+	}
+	<-done
+}
+
+type nopDefender struct {
+	name string
+}
+
+func (d *nopDefender) Check(resource string) error {
+	return nil
+}
+
+func (d *nopDefender) String() string {
+	return d.name
+}
+
+func TestChain_BlockError(t *testing.T) {
+	var log []string
+	shared := &BlockError{RuleID: "r1", Err: errors.New("limit")}
+	ts := []struct {
+		err     error
+		blocked bool
+	}{
+		{err: context.Canceled, blocked: false},
+		{err: context.DeadlineExceeded, blocked: false},
+		{err: shared, blocked: true},
+	}
+
+	for _, s := range ts {
+		c := NewChain()
+		c.Add(&testDefender{name: "a", err: s.err, log: &log}, 100)
+		err := c.Check("res")
+		if IsBlocked(err) != s.blocked {
+			t.Errorf("err %v expect blocked %v", err, s.blocked)
+		}
+	}
+	// 组件自身的错误值不会被修改
+	if shared.Resource != "" || shared.Kind != "" {
+		t.Errorf("shared block error modified %#v", shared)
+	}
+}
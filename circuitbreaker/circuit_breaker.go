@@ -2,9 +2,11 @@ package circuitbreaker
 
 import (
 	"errors"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/lanceryou/defender"
 	"github.com/lanceryou/defender/internal/base"
 )
 
@@ -34,30 +36,45 @@ const (
 	Open
 )
 
-func (s State) Load() State {
-	return State(atomic.LoadInt32((*int32)(&s)))
+func (s *State) Load() State {
+	return State(atomic.LoadInt32((*int32)(s)))
 }
 
-func (s State) Store(state State) {
-	atomic.StoreInt32((*int32)(&s), int32(state))
-}
-
-func (s State) cas(expect State, update State) bool {
-	return atomic.CompareAndSwapInt32((*int32)(&s), int32(expect), int32(update))
+func (s *State) Store(state State) {
+	atomic.StoreInt32((*int32)(s), int32(state))
 }
 
 type circuitBreaker struct {
+	resource             string
 	state                State
 	nextRetryTimestampMs int64
 	stat                 CircuitBreakerStat
 	retryTimeoutMs       int64
+	minRequest           int64
+
+	// HalfOpen 探测
+	maxProbes        int64 // 同时在途的探测数上限
+	successThreshold int64 // 连续成功多少次探测后关闭
+	probeTimeoutMs   int64 // TryPass 的探测超过该时长没有上报且名额占满时回到 Open，0 不超时
+	mu               sync.Mutex
+	probeGen         int64 // HalfOpen 周期编号，区分上一周期遗留的探测结果
+	probes           int64 // 在途探测数
+	timedProbes      int64 // 在途探测中会超时的 TryPass 探测数
+	probeDeadlineMs  int64 // 最近一次放行 TryPass 探测的时间加 probeTimeoutMs
+	successes        int64 // 连续成功的探测数
 }
 
-func newCircuitBreaker(stat CircuitBreakerStat, rt int64) *circuitBreaker {
+func newCircuitBreaker(stat CircuitBreakerStat, opt *Options) *circuitBreaker {
 	return &circuitBreaker{
+		resource:       opt.resource,
 		state:          Closed,
 		stat:           stat,
-		retryTimeoutMs: rt,
+		retryTimeoutMs: opt.retryTimeoutMs,
+		minRequest:     opt.minRequest,
+
+		maxProbes:        opt.halfOpenProbes,
+		successThreshold: opt.halfOpenSuccesses,
+		probeTimeoutMs:   opt.probeTimeoutMs,
 	}
 }
 
@@ -71,36 +88,186 @@ type CircuitBreaker struct {
 	cbList []*circuitBreaker
 }
 
+// Allow 熔断打开时返回 *defender.BlockError，errors.Is(err, CircuitBreakerOpenErr) 成立
+// 其余情况返回 fn 的结果
 func (c *CircuitBreaker) Allow(fn func() error) error {
-	for _, cb := range c.cbList {
-		if err := cb.Allow(fn); err != nil {
-			return err
-		}
+	p, err := c.tryPass(false)
+	if err != nil {
+		return err
 	}
+	// fn panic 时归还探测名额
+	defer p.release()
 
-	return nil
+	start := time.Now()
+	err = fn()
+	p.done(err, time.Since(start))
+	return err
 }
 
-/* Allow
- * 全部状态转移放这里
- *
- */
-func (c *circuitBreaker) Allow(fn func() error) error {
-	for {
-		state := c.state.Load()
-		if state == Open {
-			// state open and no reach retry time. refuse request.
-			if !c.reachRetryTimestamp(time.Now()) {
-				return CircuitBreakerOpenErr
-			}
-			// if cas fail,it means state has change,so we need load again
-			if !state.cas(Open, HalfOpen) {
-				continue
+// TryPass 两阶段接口，适用于流式调用、异步回调等调用结束时才知道结果的场景
+// 放行时返回 done，调用方在调用结束后上报错误与耗时，done 只有第一次调用生效
+// 设置了 WithProbeTimeoutMs 时，HalfOpen 时没有调用 done 的探测超时后按失败处理
+// 熔断打开时返回 *defender.BlockError
+func (c *CircuitBreaker) TryPass() (done func(err error, rt time.Duration), err error) {
+	p, err := c.tryPass(true)
+	if err != nil {
+		return nil, err
+	}
+	return p.done, nil
+}
+
+// tryPass timed 表示探测结果可能一直不上报，需要按 probeTimeoutMs 超时
+func (c *CircuitBreaker) tryPass(timed bool) (*pass, error) {
+	timed = timed && c.opt.probeTimeoutMs > 0
+	probes := make([]int64, len(c.cbList))
+	for i, cb := range c.cbList {
+		probe, err := cb.tryPass(timed)
+		if err != nil {
+			// 归还前面熔断器已经占用的探测名额
+			for j := 0; j < i; j++ {
+				c.cbList[j].release(probes[j], timed)
 			}
+			return nil, err
 		}
-		// half open try probe
-		err := c.stat.Stat(fn, c.tryUpdateState)()
-		return err
+		probes[i] = probe
+	}
+	return &pass{c: c, probes: probes, timed: timed}, nil
+}
+
+// pass 一次放行，记录各熔断器占用的探测名额
+// done 与 release 只有第一次调用生效
+type pass struct {
+	c      *CircuitBreaker
+	probes []int64
+	timed  bool
+	once   int32
+}
+
+// done 上报调用结果
+func (p *pass) done(err error, rt time.Duration) {
+	if !atomic.CompareAndSwapInt32(&p.once, 0, 1) {
+		return
+	}
+	for i, cb := range p.c.cbList {
+		cb.record(p.probes[i], p.timed, err, rt)
+	}
+}
+
+// release 调用没有实际完成，归还探测名额，不计入统计
+func (p *pass) release() {
+	if !atomic.CompareAndSwapInt32(&p.once, 0, 1) {
+		return
+	}
+	for i, cb := range p.c.cbList {
+		cb.release(p.probes[i], p.timed)
+	}
+}
+
+// tryPass
+// Open 到 HalfOpen 的状态转移放这里，其余转移在 tryUpdateState
+// HalfOpen 时只放行 maxProbes 个探测，返回当前周期编号，非探测请求返回 0
+// timed 的探测在 probeTimeoutMs 内没有上报时，名额占满后按探测失败回到 Open
+func (c *circuitBreaker) tryPass(timed bool) (probe int64, err error) {
+	if c.state.Load() == Closed {
+		return 0, nil
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.state.Load() {
+	case Closed:
+		return 0, nil
+	case Open:
+		// state open and no reach retry time. refuse request.
+		if !c.reachRetryTimestamp(now) {
+			return 0, c.blockError(now)
+		}
+		c.probeGen++
+		c.probes = 0
+		c.timedProbes = 0
+		c.successes = 0
+		c.state.Store(HalfOpen)
+	}
+	// half open try probe
+	if c.probes >= c.maxProbes {
+		if c.timedProbes > 0 && base.UnixMs(now) >= c.probeDeadlineMs {
+			// TryPass 的探测一直没有上报（调用方没有调用 done），按探测失败回到 Open，重试时间到后开始新的探测周期
+			c.updateNextRetryTimestampMs(now)
+			c.state.Store(Open)
+		}
+		return 0, c.blockError(now)
+	}
+	c.probes++
+	if timed {
+		c.timedProbes++
+		c.probeDeadlineMs = base.UnixMs(now) + c.probeTimeoutMs
+	}
+	return c.probeGen, nil
+}
+
+// peek 只判断是否拒绝，不转移状态也不占用探测名额
+func (c *circuitBreaker) peek() error {
+	now := time.Now()
+	switch c.state.Load() {
+	case Open:
+		if !c.reachRetryTimestamp(now) {
+			return c.blockError(now)
+		}
+	case HalfOpen:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.state.Load() == HalfOpen && c.probes >= c.maxProbes {
+			return c.blockError(now)
+		}
+	}
+	return nil
+}
+
+// release 放弃探测，归还探测名额
+func (c *circuitBreaker) release(probe int64, timed bool) {
+	if probe == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if probe == c.probeGen && c.state.Load() == HalfOpen {
+		c.releaseProbe(timed)
+	}
+}
+
+// releaseProbe 探测结束，调用方持有 mu
+func (c *circuitBreaker) releaseProbe(timed bool) {
+	c.probes--
+	if timed {
+		c.timedProbes--
+	}
+}
+
+// record 上报调用结果，stat 支持 StatRecorder 时带上耗时
+func (c *circuitBreaker) record(probe int64, timed bool, err error, rt time.Duration) {
+	cr := func(match bool, reach bool) {
+		c.tryUpdateState(probe, timed, match, reach)
+	}
+	if r, ok := c.stat.(StatRecorder); ok {
+		r.Record(err, rt, cr)
+		return
+	}
+	c.stat.Stat(func() error { return err }, cr)()
+}
+
+func (c *circuitBreaker) blockError(t time.Time) error {
+	retryAfter := atomic.LoadInt64(&c.nextRetryTimestampMs) - base.UnixMs(t)
+	if retryAfter < 0 {
+		// HalfOpen 探测名额已满，重试时间已过
+		retryAfter = 0
+	}
+	return &defender.BlockError{
+		Resource:   c.resource,
+		Kind:       "circuitbreaker",
+		RuleID:     c.stat.String(),
+		RetryAfter: time.Duration(retryAfter) * time.Millisecond,
+		Err:        CircuitBreakerOpenErr,
 	}
 }
 
@@ -114,28 +281,61 @@ func (c *circuitBreaker) updateNextRetryTimestampMs(t time.Time) {
 	return
 }
 
-func (c *circuitBreaker) tryUpdateState(match bool, reach bool) {
-	cur := c.state.Load()
-	if cur == Open {
+func (c *circuitBreaker) tryUpdateState(probe int64, timed bool, match bool, reach bool) {
+	// 非探测请求只可能触发 Closed 到 Open
+	if probe == 0 && !reach {
 		return
-	} else if cur == HalfOpen {
-		if !match {
-			c.state.Store(Closed)
-		} else {
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.state.Load() {
+	case HalfOpen:
+		if probe != c.probeGen {
+			return
+		}
+		c.releaseProbe(timed)
+		if match {
 			// probe failed
 			// HalfOpen to Open
 			c.updateNextRetryTimestampMs(time.Now())
 			c.state.Store(Open)
+			return
+		}
+		c.successes++
+		if c.successes >= c.successThreshold {
+			// HalfOpen to Closed，清空统计，避免窗口内熔断前的失败再次打开熔断
+			if r, ok := c.stat.(statResetter); ok {
+				r.Reset()
+			}
+			c.state.Store(Closed)
+		}
+	case Closed:
+		if reach && c.reachMinRequest() {
+			//  Closed to Open
+			c.state.Store(Open)
+			c.updateNextRetryTimestampMs(time.Now())
 		}
-	} else if cur == Closed && reach {
-		//  Closed to Open
-		c.state.Store(Open)
-		c.updateNextRetryTimestampMs(time.Now())
 	}
 }
 
+// reachMinRequest 窗口内请求数是否达到熔断器的最小请求数
+func (c *circuitBreaker) reachMinRequest() bool {
+	if c.minRequest <= 0 {
+		return true
+	}
+	counter, ok := c.stat.(requestCounter)
+	if !ok {
+		return true
+	}
+	return counter.Total() >= c.minRequest
+}
+
 func NewCircuitBreaker(option ...Option) *CircuitBreaker {
-	var opt Options
+	opt := Options{
+		halfOpenProbes:    1,
+		halfOpenSuccesses: 1,
+	}
 	for _, o := range option {
 		o(&opt)
 	}
@@ -145,7 +345,7 @@ func NewCircuitBreaker(option ...Option) *CircuitBreaker {
 	}
 
 	for _, stat := range opt.stats {
-		cb.cbList = append(cb.cbList, newCircuitBreaker(stat, opt.retryTimeoutMs))
+		cb.cbList = append(cb.cbList, newCircuitBreaker(stat, &opt))
 	}
 
 	return cb
@@ -0,0 +1,212 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/pkg/timering"
+)
+
+var testErr = errors.New("test err")
+
+func newTestBreaker(opts ...Option) *CircuitBreaker {
+	stat := NewErrStat(timering.NewTimeRing(1000, 10), 0.5, 0)
+	return NewCircuitBreaker(append([]Option{WithCircuitBreakerStat(stat), WithRetryTimeoutMs(50)}, opts...)...)
+}
+
+func TestCircuitBreaker_TryPass(t *testing.T) {
+	cb := newTestBreaker()
+	done, err := cb.TryPass()
+	if err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+	done(testErr, time.Millisecond)
+	// done 只有第一次生效
+	done(nil, time.Millisecond)
+
+	if _, err = cb.TryPass(); !errors.Is(err, CircuitBreakerOpenErr) {
+		t.Fatalf("expect open, but %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	done, err = cb.TryPass()
+	if err != nil {
+		t.Fatalf("expect probe pass, but %v", err)
+	}
+	done(nil, time.Millisecond)
+	if state := cb.cbList[0].state.Load(); state != Closed {
+		t.Errorf("expect closed, but %v", state)
+	}
+}
+
+func TestCircuitBreaker_MinRequest(t *testing.T) {
+	ts := []struct {
+		cb *CircuitBreaker
+	}{
+		{cb: newTestBreaker(WithMinRequest(3))},
+		{cb: NewCircuitBreaker(WithCircuitBreakerStat(NewErrStat(timering.NewTimeRing(1000, 10), 0.5, 0, WithStatMinRequest(3))), WithRetryTimeoutMs(50))},
+	}
+
+	for _, s := range ts {
+		for i := 0; i < 2; i++ {
+			if err := s.cb.Allow(func() error { return testErr }); err != testErr {
+				t.Fatalf("expect %v, but %v", testErr, err)
+			}
+		}
+		s.cb.Allow(func() error { return testErr })
+		if err := s.cb.Allow(func() error { return nil }); !errors.Is(err, CircuitBreakerOpenErr) {
+			t.Errorf("expect open, but %v", err)
+		}
+	}
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	cb := newTestBreaker(WithHalfOpenProbes(2), WithHalfOpenSuccessThreshold(3))
+	cb.Allow(func() error { return testErr })
+
+	time.Sleep(60 * time.Millisecond)
+	var dones []func(err error, rt time.Duration)
+	for i := 0; i < 2; i++ {
+		done, err := cb.TryPass()
+		if err != nil {
+			t.Fatalf("expect probe pass, but %v", err)
+		}
+		dones = append(dones, done)
+	}
+	// 探测名额已满
+	if _, err := cb.TryPass(); !errors.Is(err, CircuitBreakerOpenErr) {
+		t.Fatalf("expect open, but %v", err)
+	}
+
+	for _, done := range dones {
+		done(nil, time.Millisecond)
+	}
+	if state := cb.cbList[0].state.Load(); state != HalfOpen {
+		t.Fatalf("expect half open, but %v", state)
+	}
+	if err := cb.Allow(func() error { return nil }); err != nil {
+		t.Fatalf("expect probe pass, but %v", err)
+	}
+	if state := cb.cbList[0].state.Load(); state != Closed {
+		t.Errorf("expect closed, but %v", state)
+	}
+}
+
+func TestErrorClassifier(t *testing.T) {
+	notFound := errors.New("not found")
+	ts := []struct {
+		classifier ErrorClassifier
+		err        error
+		open       bool
+	}{
+		{classifier: IgnoreErrors(notFound), err: notFound, open: false},
+		{classifier: IgnoreErrors(notFound), err: testErr, open: true},
+		{classifier: MatchErrors(testErr), err: notFound, open: false},
+		{classifier: func(err error) bool { return err != notFound }, err: testErr, open: true},
+	}
+
+	for _, s := range ts {
+		stat := NewErrStat(timering.NewTimeRing(1000, 10), 0.5, 0, WithStatErrorClassifier(s.classifier))
+		cb := NewCircuitBreaker(WithCircuitBreakerStat(stat), WithRetryTimeoutMs(50))
+		cb.Allow(func() error { return s.err })
+		err := cb.Allow(func() error { return nil })
+		if open := errors.Is(err, CircuitBreakerOpenErr); open != s.open {
+			t.Errorf("expect open %v, but %v", s.open, open)
+		}
+	}
+}
+
+func TestCircuitBreaker_Enter(t *testing.T) {
+	cb := newTestBreaker(WithResource("res"))
+	g := defender.NewGroup()
+	g.Register(cb)
+
+	e, err := g.Enter(context.Background(), "res")
+	if err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+	e.Exit(testErr)
+
+	if _, err = g.Enter(context.Background(), "res"); !errors.Is(err, CircuitBreakerOpenErr) {
+		t.Fatalf("expect open, but %v", err)
+	}
+	if err = g.Check("res"); !errors.Is(err, CircuitBreakerOpenErr) {
+		t.Errorf("expect open, but %v", err)
+	}
+	if err = g.Check("other"); err != nil {
+		t.Errorf("expect other resource pass, but %v", err)
+	}
+}
+
+func TestCircuitBreaker_ProbeLeak(t *testing.T) {
+	cb := newTestBreaker(WithProbeTimeoutMs(20))
+	cb.Allow(func() error { return testErr })
+	time.Sleep(60 * time.Millisecond)
+
+	// 探测 panic 时归还探测名额
+	func() {
+		defer func() {
+			recover()
+		}()
+		cb.Allow(func() error {
+			panic("probe panic")
+		})
+	}()
+	if err := cb.Allow(func() error { return nil }); err != nil {
+		t.Fatalf("expect probe pass after panic, but %v", err)
+	}
+	if state := cb.cbList[0].state.Load(); state != Closed {
+		t.Fatalf("expect closed, but %v", state)
+	}
+
+	// TryPass 的探测一直没有上报，超时后回到 Open 并重新探测
+	cb.Allow(func() error { return testErr })
+	time.Sleep(60 * time.Millisecond)
+	if _, err := cb.TryPass(); err != nil {
+		t.Fatalf("expect probe pass, but %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := cb.TryPass(); !errors.Is(err, CircuitBreakerOpenErr) {
+		t.Fatalf("expect open, but %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	done, err := cb.TryPass()
+	if err != nil {
+		t.Fatalf("expect new probe pass, but %v", err)
+	}
+	done(nil, time.Millisecond)
+	if state := cb.cbList[0].state.Load(); state != Closed {
+		t.Errorf("expect closed, but %v", state)
+	}
+}
+
+func TestCircuitBreaker_SlowProbe(t *testing.T) {
+	cb := newTestBreaker(WithProbeTimeoutMs(20))
+	cb.Allow(func() error { return testErr })
+	time.Sleep(60 * time.Millisecond)
+
+	// Allow 的探测执行时间超过 probeTimeoutMs 也不会回到 Open
+	probed := make(chan error, 1)
+	go func() {
+		probed <- cb.Allow(func() error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+	}()
+	time.Sleep(30 * time.Millisecond)
+	if err := cb.Allow(func() error { return nil }); !errors.Is(err, CircuitBreakerOpenErr) {
+		t.Fatalf("expect probe slots full, but %v", err)
+	}
+	if state := cb.cbList[0].state.Load(); state != HalfOpen {
+		t.Fatalf("expect half open, but %v", state)
+	}
+	if err := <-probed; err != nil {
+		t.Fatalf("expect probe pass, but %v", err)
+	}
+	if state := cb.cbList[0].state.Load(); state != Closed {
+		t.Errorf("expect closed, but %v", state)
+	}
+}
@@ -0,0 +1,33 @@
+package circuitbreaker
+
+import (
+	"errors"
+)
+
+// ErrorClassifier 判断非 nil 的 err 是否计为失败
+// 返回 false 的错误（如 404、参数校验失败等业务错误）按正常响应统计，不会触发熔断
+type ErrorClassifier func(err error) bool
+
+// IgnoreErrors 与 errs 中任意一个 errors.Is 匹配的错误不计为失败，其余错误计为失败
+func IgnoreErrors(errs ...error) ErrorClassifier {
+	return func(err error) bool {
+		for _, e := range errs {
+			if errors.Is(err, e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchErrors 只有与 errs 中任意一个 errors.Is 匹配的错误计为失败
+func MatchErrors(errs ...error) ErrorClassifier {
+	return func(err error) bool {
+		for _, e := range errs {
+			if errors.Is(err, e) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package circuitbreaker
+
+import (
+	"github.com/lanceryou/defender"
+)
+
+// Check 实现 defender.Defender
+// 只在熔断打开或探测名额已满时拒绝，不占用探测名额也不统计结果
+// 需要统计调用结果请通过 defender.Enter 使用
+func (c *CircuitBreaker) Check(resource string) error {
+	if !c.match(resource) {
+		return nil
+	}
+	for _, cb := range c.cbList {
+		if err := cb.peek(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckEntry 实现 defender.EntryChecker，放行后在 Exit 时上报调用结果
+func (c *CircuitBreaker) CheckEntry(e *defender.Entry) error {
+	if !c.match(e.Resource()) {
+		return nil
+	}
+	p, err := c.tryPass(false)
+	if err != nil {
+		return err
+	}
+	e.SetValue(c, p)
+	return nil
+}
+
+// Exit 实现 defender.ExitHandler，按 Entry 的耗时与 err 上报
+// 被后续组件拒绝的调用没有实际执行，只归还探测名额，不计入统计
+func (c *CircuitBreaker) Exit(e *defender.Entry, err error) {
+	p, ok := e.Value(c).(*pass)
+	if !ok {
+		return
+	}
+	if e.BlockError() != nil {
+		p.release()
+		return
+	}
+	p.done(err, e.RT())
+}
+
+func (c *CircuitBreaker) String() string {
+	if c.opt.resource == "" {
+		return "circuitbreaker"
+	}
+	return "circuitbreaker:" + c.opt.resource
+}
+
+func (c *CircuitBreaker) match(resource string) bool {
+	return c.opt.resource == "" || c.opt.resource == resource
+}
@@ -0,0 +1,32 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Execute 经过熔断执行 fn 并直接返回结果
+// ctx 已经结束时不执行 fn，直接返回 ctx.Err()，不计入统计
+// fn 返回 context.Canceled 视为调用方主动放弃，默认既不计为失败也不计为成功，
+// 只归还 HalfOpen 的探测名额，可通过 WithCountCanceled 计为失败
+func Execute[T any](ctx context.Context, cb *CircuitBreaker, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	p, err := cb.tryPass(false)
+	if err != nil {
+		return zero, err
+	}
+	defer p.release()
+
+	start := time.Now()
+	result, err := fn(ctx)
+	if err != nil && !cb.opt.countCanceled && errors.Is(err, context.Canceled) {
+		return result, err
+	}
+	p.done(err, time.Since(start))
+	return result, err
+}
@@ -0,0 +1,85 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExecute(t *testing.T) {
+	cb := newTestBreaker()
+	v, err := Execute(context.Background(), cb, func(ctx context.Context) (int, error) {
+		return 1, nil
+	})
+	if v != 1 || err != nil {
+		t.Fatalf("expect 1, nil but %v, %v", v, err)
+	}
+
+	// context.Canceled 默认不计为失败
+	_, err = Execute(context.Background(), cb, func(ctx context.Context) (int, error) {
+		return 0, context.Canceled
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect canceled, but %v", err)
+	}
+	if _, err = Execute(context.Background(), cb, func(ctx context.Context) (int, error) {
+		return 2, nil
+	}); err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var called bool
+	_, err = Execute(ctx, cb, func(ctx context.Context) (int, error) {
+		called = true
+		return 0, nil
+	})
+	if called || !errors.Is(err, context.Canceled) {
+		t.Errorf("expect not called and canceled, but %v, %v", called, err)
+	}
+}
+
+func TestExecute_CanceledProbe(t *testing.T) {
+	ts := []struct {
+		countCanceled bool
+		expect        State
+	}{
+		// 取消的探测不计入统计，只归还探测名额
+		{countCanceled: false, expect: HalfOpen},
+		{countCanceled: true, expect: Open},
+	}
+
+	for _, s := range ts {
+		cb := newTestBreaker(WithCountCanceled(s.countCanceled))
+		cb.Allow(func() error { return testErr })
+		time.Sleep(60 * time.Millisecond)
+
+		_, err := Execute(context.Background(), cb, func(ctx context.Context) (int, error) {
+			return 0, context.Canceled
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expect canceled, but %v", err)
+		}
+		if state := cb.cbList[0].state.Load(); state != s.expect {
+			t.Errorf("count canceled %v: expect state %v, but %v", s.countCanceled, s.expect, state)
+		}
+	}
+
+	// 归还的探测名额可以被下一个请求使用
+	cb := newTestBreaker()
+	cb.Allow(func() error { return testErr })
+	time.Sleep(60 * time.Millisecond)
+	Execute(context.Background(), cb, func(ctx context.Context) (int, error) {
+		return 0, context.Canceled
+	})
+	if _, err := Execute(context.Background(), cb, func(ctx context.Context) (int, error) {
+		return 1, nil
+	}); err != nil {
+		t.Fatalf("expect probe pass, but %v", err)
+	}
+	if state := cb.cbList[0].state.Load(); state != Closed {
+		t.Errorf("expect closed, but %v", state)
+	}
+}
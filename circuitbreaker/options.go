@@ -3,8 +3,15 @@ package circuitbreaker
 // 拦截器设计
 // metrics 资源设计
 type Options struct {
+	resource       string
 	stats          []CircuitBreakerStat
 	retryTimeoutMs int64
+	countCanceled  bool
+	minRequest     int64
+
+	halfOpenProbes    int64
+	halfOpenSuccesses int64
+	probeTimeoutMs    int64
 }
 
 type Option func(*Options)
@@ -20,3 +27,51 @@ func WithRetryTimeoutMs(retryTimeoutMs int64) Option {
 		options.retryTimeoutMs = retryTimeoutMs
 	}
 }
+
+// WithMinRequest 统计窗口内请求数低于 n 时不会从 Closed 转为 Open，对全部统计生效
+// 统计需要实现 Total() int64，否则不受该限制
+func WithMinRequest(n int64) Option {
+	return func(options *Options) {
+		options.minRequest = n
+	}
+}
+
+// WithHalfOpenProbes HalfOpen 时同时放行的探测请求数，默认 1，其余请求按熔断打开拒绝
+func WithHalfOpenProbes(n int64) Option {
+	return func(options *Options) {
+		if n > 0 {
+			options.halfOpenProbes = n
+		}
+	}
+}
+
+// WithHalfOpenSuccessThreshold HalfOpen 时连续成功多少次探测后关闭熔断，默认 1
+func WithHalfOpenSuccessThreshold(m int64) Option {
+	return func(options *Options) {
+		if m > 0 {
+			options.halfOpenSuccesses = m
+		}
+	}
+}
+
+// WithProbeTimeoutMs TryPass 放行的探测超过该时长没有上报结果且探测名额占满时回到 Open，默认 0 不超时
+// 避免调用方没有调用 done 时熔断一直停在 HalfOpen 拒绝全部请求，Allow、Execute 与 Entry 的探测不受影响
+func WithProbeTimeoutMs(probeTimeoutMs int64) Option {
+	return func(options *Options) {
+		options.probeTimeoutMs = probeTimeoutMs
+	}
+}
+
+// WithResource 熔断保护的资源名，注册到 defender 时为空表示对所有资源生效
+func WithResource(resource string) Option {
+	return func(options *Options) {
+		options.resource = resource
+	}
+}
+
+// WithCountCanceled Execute 中 fn 返回 context.Canceled 时是否计为失败，默认不计
+func WithCountCanceled(count bool) Option {
+	return func(options *Options) {
+		options.countCanceled = count
+	}
+}
@@ -1,6 +1,8 @@
 package circuitbreaker
 
 import (
+	"time"
+
 	"github.com/lanceryou/defender/internal/circuitbreaker/errorstat"
 	"github.com/lanceryou/defender/internal/circuitbreaker/slowstat"
 	"github.com/lanceryou/defender/pkg/timering"
@@ -12,12 +14,58 @@ type CircuitBreakerStat interface {
 	String() string
 }
 
-func NewSlowStat(slowResponseMs int64, ring *timering.TimeRing, ratio float64) CircuitBreakerStat {
-	return slowstat.NewSlowStat(slowResponseMs, ring, ratio)
+// StatRecorder 可以直接上报调用结果的统计，TryPass 依赖该接口获取调用耗时
+// 未实现时按 Stat 上报，耗时记为 0
+type StatRecorder interface {
+	Record(err error, rt time.Duration, cr func(match bool, reach bool))
+}
+
+type statOptions struct {
+	minRequest int64
+	classifier ErrorClassifier
+}
+
+type StatOption func(*statOptions)
+
+// WithStatMinRequest 窗口内请求数低于 n 时该统计不触发熔断，避免少量请求的偶发错误打开熔断
+func WithStatMinRequest(n int64) StatOption {
+	return func(o *statOptions) {
+		o.minRequest = n
+	}
+}
+
+// requestCounter 可以获取窗口内请求数的统计，熔断器的最小请求数依赖该接口
+type requestCounter interface {
+	Total() int64
+}
+
+// statResetter 可以清空的统计，熔断关闭时清空窗口
+type statResetter interface {
+	Reset()
+}
+
+// WithStatErrorClassifier 按 classifier 判断错误是否计为失败，不计为失败的错误按正常响应统计
+// 错误统计不计为错误，慢调用统计不计为慢调用，默认全部非 nil 错误计为失败
+func WithStatErrorClassifier(classifier ErrorClassifier) StatOption {
+	return func(o *statOptions) {
+		o.classifier = classifier
+	}
+}
+
+func NewSlowStat(slowResponseMs int64, ring *timering.TimeRing, ratio float64, opts ...StatOption) CircuitBreakerStat {
+	var o statOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return slowstat.NewSlowStat(slowResponseMs, ring, ratio, o.minRequest, o.classifier)
 }
 
-func NewErrStat(ring *timering.TimeRing, ratio float64, total int64) CircuitBreakerStat {
-	return errorstat.NewErrorStat(ring, ratio, total)
+func NewErrStat(ring *timering.TimeRing, ratio float64, total int64, opts ...StatOption) CircuitBreakerStat {
+	var o statOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return errorstat.NewErrorStat(ring, ratio, total, o.minRequest, o.classifier)
 }
 
 var (
@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/lanceryou/defender/flow"
+)
+
+var (
+	ServerUnavailableErr = errors.New("cluster: token server unavailable")
+	RequestTimeoutErr    = errors.New("cluster: token request timeout")
+)
+
+// TokenClient 向 token server 申请令牌，实现 flow.TokenService
+// 连接失败或请求超时返回错误，由流控退化为本地限流
+type TokenClient struct {
+	opt  Options
+	addr string
+
+	mu       sync.Mutex
+	client   *rpc.Client
+	dialFail time.Time
+}
+
+func NewTokenClient(addr string, opts ...Option) *TokenClient {
+	opt := Options{
+		requestTimeout: 20 * time.Millisecond,
+		dialTimeout:    100 * time.Millisecond,
+		redialInterval: time.Second,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &TokenClient{
+		opt:  opt,
+		addr: addr,
+	}
+}
+
+// RequestToken 实现 flow.TokenService
+func (c *TokenClient) RequestToken(ctx context.Context, ruleID string, count int64) (flow.TokenResult, error) {
+	client, err := c.conn()
+	if err != nil {
+		return flow.TokenResult{}, err
+	}
+
+	req := &TokenRequest{RuleID: ruleID, Count: count}
+	resp := &TokenResponse{}
+	call := client.Go(serviceName+".RequestToken", req, resp, make(chan *rpc.Call, 1))
+
+	t := time.NewTimer(c.opt.requestTimeout)
+	defer t.Stop()
+	select {
+	case <-call.Done:
+		if call.Error != nil {
+			c.reset(client, call.Error)
+			return flow.TokenResult{}, call.Error
+		}
+		return flow.TokenResult{
+			Status: flow.TokenStatus(resp.Status),
+			Wait:   time.Duration(resp.WaitNs),
+		}, nil
+	case <-t.C:
+		return flow.TokenResult{}, RequestTimeoutErr
+	case <-ctx.Done():
+		return flow.TokenResult{}, ctx.Err()
+	}
+}
+
+// Close 关闭连接
+func (c *TokenClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		return nil
+	}
+	err := c.client.Close()
+	c.client = nil
+	return err
+}
+
+// conn 懒连接，连接失败后 redialInterval 内不再重试
+func (c *TokenClient) conn() (*rpc.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+	if time.Since(c.dialFail) < c.opt.redialInterval {
+		return nil, ServerUnavailableErr
+	}
+
+	conn, err := net.DialTimeout("tcp", c.addr, c.opt.dialTimeout)
+	if err != nil {
+		c.dialFail = time.Now()
+		return nil, ServerUnavailableErr
+	}
+	c.client = rpc.NewClient(conn)
+	return c.client, nil
+}
+
+// reset 连接断开后丢弃，下次请求重新连接
+// 服务端返回的业务错误（如规则不存在）不影响连接
+func (c *TokenClient) reset(client *rpc.Client, err error) {
+	if _, ok := err.(rpc.ServerError); ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == client {
+		c.client.Close()
+		c.client = nil
+		c.dialFail = time.Now()
+	}
+}
@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lanceryou/defender/flow"
+)
+
+func TestCluster_Fallback(t *testing.T) {
+	rule := &flow.Rule{
+		Resource:                 "res",
+		Threshold:                4,
+		ClusterMode:              true,
+		ClusterFallbackThreshold: 1,
+	}
+	service, err := NewLocalTokenService(rule)
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+	server := NewTokenServer(service)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+	go server.Serve(ln)
+
+	client := NewTokenClient(ln.Addr().String(), WithRequestTimeout(time.Second))
+	defer client.Close()
+	flow.SetTokenService(client)
+	defer flow.SetTokenService(nil)
+
+	// 两个客户端共享集群阈值 4
+	c1, _ := flow.NewController(rule)
+	c2, _ := flow.NewController(rule)
+	var pass int
+	for i := 0; i < 4; i++ {
+		if c1.Check("res") == nil {
+			pass++
+		}
+		if c2.Check("res") == nil {
+			pass++
+		}
+	}
+	if pass != 4 {
+		t.Errorf("expect cluster pass 4, but %v", pass)
+	}
+
+	// token server 不可用时退化为本地阈值 1
+	server.Close()
+	client.Close()
+	flow.SetTokenService(NewTokenClient(ln.Addr().String()))
+	c3, _ := flow.NewController(rule)
+	pass = 0
+	for i := 0; i < 4; i++ {
+		if c3.Check("res") == nil {
+			pass++
+		}
+	}
+	if pass != 1 {
+		t.Errorf("expect fallback pass 1, but %v", pass)
+	}
+
+	if _, err := service.RequestToken(context.Background(), "none", 1); err != NoRuleErr {
+		t.Errorf("expect err %v, but %v", NoRuleErr, err)
+	}
+}
+
+func TestLocalTokenService_RequestToken(t *testing.T) {
+	service, err := NewLocalTokenService(&flow.Rule{Resource: "res", Threshold: 5})
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+
+	ts := []struct {
+		count  int64
+		status flow.TokenStatus
+	}{
+		{count: 3, status: flow.TokenOK},
+		// 剩余 2 个令牌不足 3 个，一个都不占用
+		{count: 3, status: flow.TokenBlocked},
+		{count: 2, status: flow.TokenOK},
+		{count: 1, status: flow.TokenBlocked},
+	}
+	for i, s := range ts {
+		result, err := service.RequestToken(context.Background(), "res", s.count)
+		if err != nil {
+			t.Fatalf("unexpect err %v", err)
+		}
+		if result.Status != s.status {
+			t.Errorf("case %v: expect status %v, but %v", i, s.status, result.Status)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/flow"
+)
+
+var (
+	NoRuleErr = errors.New("cluster: no rule")
+)
+
+// LocalTokenService 进程内的令牌服务
+// 是 token server 的计数核心，也可以在测试或单进程部署时直接作为 flow.TokenService
+type LocalTokenService struct {
+	mu          sync.RWMutex
+	controllers map[string]*flow.Controller
+}
+
+func NewLocalTokenService(rules ...*flow.Rule) (*LocalTokenService, error) {
+	s := &LocalTokenService{}
+	if err := s.LoadRules(rules...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadRules 整体替换规则，Threshold 为集群阈值
+// 服务端只做计数，排队与调用方区分由客户端处理
+func (s *LocalTokenService) LoadRules(rules ...*flow.Rule) error {
+	controllers := make(map[string]*flow.Controller, len(rules))
+	for _, r := range rules {
+		rule := *r
+		rule.ClusterMode = false
+		rule.LimitApp = ""
+		rule.ControlBehavior = flow.Reject
+		c, err := flow.NewController(&rule)
+		if err != nil {
+			return err
+		}
+		controllers[ruleID(r)] = c
+	}
+
+	s.mu.Lock()
+	s.controllers = controllers
+	s.mu.Unlock()
+	return nil
+}
+
+// RequestToken 实现 flow.TokenService
+func (s *LocalTokenService) RequestToken(ctx context.Context, id string, count int64) (flow.TokenResult, error) {
+	s.mu.RLock()
+	c, ok := s.controllers[id]
+	s.mu.RUnlock()
+	if !ok {
+		return flow.TokenResult{}, NoRuleErr
+	}
+
+	// 令牌不足时一个都不占用
+	if err := c.TryAcquire(count); err != nil {
+		result := flow.TokenResult{Status: flow.TokenBlocked}
+		if be, ok := defender.AsBlockError(err); ok {
+			result.Wait = be.RetryAfter
+		}
+		return result, nil
+	}
+	return flow.TokenResult{Status: flow.TokenOK}, nil
+}
+
+func ruleID(r *flow.Rule) string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Resource
+}
@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"time"
+)
+
+type Options struct {
+	requestTimeout time.Duration
+	dialTimeout    time.Duration
+	redialInterval time.Duration
+}
+
+type Option func(*Options)
+
+// WithRequestTimeout 单次申请超时，超时后退化为本地限流，默认 20ms
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.requestTimeout = timeout
+	}
+}
+
+// WithDialTimeout 连接超时，默认 100ms
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithRedialInterval 连接失败后的重连间隔，期间直接退化为本地限流，默认 1s
+func WithRedialInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.redialInterval = interval
+	}
+}
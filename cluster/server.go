@@ -0,0 +1,102 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+const serviceName = "TokenService"
+
+// TokenRequest 令牌申请
+type TokenRequest struct {
+	RuleID string
+	Count  int64
+}
+
+// TokenResponse 令牌申请结果
+type TokenResponse struct {
+	Status int32
+	WaitNs int64
+}
+
+// TokenServer 嵌入式 token server，基于 net/rpc 的 TCP 服务
+// 集群中选一个进程启动，其他进程通过 TokenClient 申请令牌
+type TokenServer struct {
+	service *LocalTokenService
+	srv     *rpc.Server
+
+	mu sync.Mutex
+	ln net.Listener
+}
+
+func NewTokenServer(service *LocalTokenService) *TokenServer {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(serviceName, &rpcService{service: service}); err != nil {
+		panic(err)
+	}
+	return &TokenServer{
+		service: service,
+		srv:     srv,
+	}
+}
+
+// ListenAndServe 监听 addr 并阻塞处理请求
+func (s *TokenServer) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve 在 ln 上阻塞处理请求，Close 后返回
+func (s *TokenServer) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	s.ln = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.srv.ServeConn(conn)
+	}
+}
+
+// Addr 监听地址，未启动时为 nil
+func (s *TokenServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Close 停止监听，已建立的连接由客户端断开
+func (s *TokenServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Close()
+}
+
+type rpcService struct {
+	service *LocalTokenService
+}
+
+func (r *rpcService) RequestToken(req *TokenRequest, resp *TokenResponse) error {
+	result, err := r.service.RequestToken(context.Background(), req.RuleID, req.Count)
+	if err != nil {
+		return err
+	}
+	resp.Status = int32(result.Status)
+	resp.WaitNs = int64(result.Wait / time.Nanosecond)
+	return nil
+}
@@ -0,0 +1,131 @@
+package concurrency
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/lanceryou/defender"
+)
+
+var (
+	ConcurrencyLimitErr = errors.New("concurrency limit exceeded")
+)
+
+// Limiter 限制资源同时执行的调用数
+// 准入时加一，调用结束时减一
+// 只有 defender.Enter 与 Allow 会占用名额；defender.Check 没有调用结束通知，
+// 不占用名额，只在 Enter/Allow 的调用已经占满时拒绝，单独使用 Check 不会限制并发
+type Limiter struct {
+	opt      Options
+	max      int64
+	inflight int64
+}
+
+func NewLimiter(max int64, opts ...Option) *Limiter {
+	if max <= 0 {
+		panic(fmt.Errorf("concurrency: max %v must be positive", max))
+	}
+	opt := Options{
+		priority: defaultPriority,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &Limiter{
+		opt: opt,
+		max: max,
+	}
+}
+
+// Acquire 占用一个并发名额，成功后必须调用 Release
+func (l *Limiter) Acquire() bool {
+	for {
+		cur := atomic.LoadInt64(&l.inflight)
+		if cur >= l.max {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&l.inflight, cur, cur+1) {
+			return true
+		}
+	}
+}
+
+// Release 归还并发名额
+func (l *Limiter) Release() {
+	atomic.AddInt64(&l.inflight, -1)
+}
+
+// InFlight 当前正在执行的调用数
+func (l *Limiter) InFlight() int64 {
+	return atomic.LoadInt64(&l.inflight)
+}
+
+// Max 并发上限
+func (l *Limiter) Max() int64 {
+	return l.max
+}
+
+// Allow 占用名额执行 fn，超过上限返回 *defender.BlockError
+func (l *Limiter) Allow(fn func() error) error {
+	if !l.Acquire() {
+		return l.blockError(l.opt.resource)
+	}
+	defer l.Release()
+	return fn()
+}
+
+// Check 实现 defender.Defender
+// 只判断 Enter/Allow 占用的并发是否已满，不占用名额，不能单独用来限制并发
+func (l *Limiter) Check(resource string) error {
+	if !l.match(resource) {
+		return nil
+	}
+	if l.InFlight() >= l.max {
+		return l.blockError(resource)
+	}
+	return nil
+}
+
+// CheckEntry 实现 defender.EntryChecker，占用名额直到 Exit
+func (l *Limiter) CheckEntry(e *defender.Entry) error {
+	if !l.match(e.Resource()) {
+		return nil
+	}
+	if !l.Acquire() {
+		return l.blockError(e.Resource())
+	}
+	return nil
+}
+
+// Exit 实现 defender.ExitHandler，归还名额
+func (l *Limiter) Exit(e *defender.Entry, err error) {
+	if l.match(e.Resource()) {
+		l.Release()
+	}
+}
+
+func (l *Limiter) String() string {
+	if l.opt.resource == "" {
+		return "concurrency"
+	}
+	return "concurrency:" + l.opt.resource
+}
+
+// Priority 实现 defender.Prioritized
+func (l *Limiter) Priority() int {
+	return l.opt.priority
+}
+
+func (l *Limiter) match(resource string) bool {
+	return l.opt.resource == "" || l.opt.resource == resource
+}
+
+func (l *Limiter) blockError(resource string) error {
+	return &defender.BlockError{
+		Resource: resource,
+		Kind:     "concurrency",
+		Err:      ConcurrencyLimitErr,
+	}
+}
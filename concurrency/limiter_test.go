@@ -0,0 +1,75 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lanceryou/defender"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	l := NewLimiter(2)
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- l.Allow(func() error {
+				started <- struct{}{}
+				<-release
+				return nil
+			})
+		}()
+	}
+	<-started
+	<-started
+
+	if l.InFlight() != 2 {
+		t.Fatalf("expect inflight 2, but %v", l.InFlight())
+	}
+	if err := l.Allow(func() error { return nil }); !errors.Is(err, ConcurrencyLimitErr) {
+		t.Errorf("expect limit err, but %v", err)
+	}
+	// Check 不占用名额，只在已经占满时拒绝
+	if err := l.Check("res"); !errors.Is(err, ConcurrencyLimitErr) {
+		t.Errorf("expect limit err, but %v", err)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("unexpect err %v", err)
+		}
+	}
+	if l.InFlight() != 0 {
+		t.Errorf("expect inflight 0, but %v", l.InFlight())
+	}
+}
+
+func TestLimiter_Enter(t *testing.T) {
+	l := NewLimiter(1, WithResource("res"))
+	g := defender.NewGroup()
+	g.Register(l)
+
+	e, err := g.Enter(context.Background(), "res")
+	if err != nil {
+		t.Fatalf("expect pass, but %v", err)
+	}
+	if _, err = g.Enter(context.Background(), "res"); !errors.Is(err, ConcurrencyLimitErr) {
+		t.Errorf("expect limit err, but %v", err)
+	}
+	if _, err = g.Enter(context.Background(), "other"); err != nil {
+		t.Errorf("expect other resource pass, but %v", err)
+	}
+
+	e.Exit(nil)
+	if l.InFlight() != 0 {
+		t.Errorf("expect inflight 0, but %v", l.InFlight())
+	}
+	e, err = g.Enter(context.Background(), "res")
+	if err != nil {
+		t.Fatalf("expect pass after exit, but %v", err)
+	}
+	e.Exit(nil)
+}
@@ -1,25 +1,103 @@
 package defender
 
+import (
+	"context"
+)
+
 // 治理接口
 type Defender interface {
 	Check(resource string) error
 	String() string
 }
 
+// EntryChecker 需要感知调用上下文的治理组件
+// 实现该接口后 Entry 准入时调用 CheckEntry 而不是 Check
+type EntryChecker interface {
+	CheckEntry(e *Entry) error
+}
+
+// ArgsChecker 需要感知调用参数的治理组件，如热点参数流控
+// 实现该接口后 CheckWithArgs 调用 CheckArgs 而不是 Check
+type ArgsChecker interface {
+	CheckArgs(resource string, args []interface{}) error
+}
+
+// ExitHandler 需要感知调用结束的治理组件（耗时，错误）
+// 只有准入通过的组件才会收到 Exit
+type ExitHandler interface {
+	Exit(e *Entry, err error)
+}
+
 var (
-	defenderMap = make(map[string]Defender)
+	defaultGroup = NewGroup()
 )
 
+// SetDefender 按组件自身优先级注册，未实现 Prioritized 使用 DefaultPriority
 func SetDefender(defender Defender) {
-	defenderMap[defender.String()] = defender
+	defaultGroup.Register(defender)
+}
+
+// Register 注册到全局，同名组件原子替换
+func Register(defender Defender) {
+	defaultGroup.Register(defender)
+}
+
+// AddDefender 按指定优先级注册到全局
+func AddDefender(defender Defender, priority int) {
+	defaultGroup.AddDefender(defender, priority)
+}
+
+// InsertBefore 插入到 name 之前
+func InsertBefore(name string, defender Defender) bool {
+	return defaultGroup.InsertBefore(name, defender)
+}
+
+// InsertAfter 插入到 name 之后
+func InsertAfter(name string, defender Defender) bool {
+	return defaultGroup.InsertAfter(name, defender)
+}
+
+// Unregister 从全局注销
+func Unregister(name string) bool {
+	return defaultGroup.Unregister(name)
+}
+
+// Replace 整体替换全局组件，用于配置重载
+func Replace(defenders ...Defender) {
+	defaultGroup.Replace(defenders...)
+}
+
+// Update 在副本上批量修改全局组件后一次性发布
+func Update(fn func(c *Chain)) {
+	defaultGroup.Update(fn)
+}
+
+// List 按执行顺序返回全局组件
+func List() []Defender {
+	return defaultGroup.List()
 }
 
 // 检测
+// 只做准入判断，不跟踪调用结束，需要完整生命周期请使用 Enter
 func Check(resource string) error {
-	for _, v := range defenderMap {
-		if err := v.Check(resource); err != nil {
-			return err
-		}
-	}
-	return nil
+	return defaultGroup.Check(resource)
+}
+
+// CheckWithArgs 带调用参数的检测
+func CheckWithArgs(resource string, args ...interface{}) error {
+	return defaultGroup.CheckWithArgs(resource, args...)
+}
+
+// Enter 资源准入，通过后调用方必须调用 Exit
+// 熔断、限流、隔离等组件在准入时收到 CheckEntry，调用结束时收到 Exit
+// 包级函数不能与 Entry 类型同名，所以准入入口命名为 Enter
+//
+//	e, err := defender.Enter(ctx, "db.query")
+//	if err != nil {
+//		return err
+//	}
+//	err = query()
+//	e.Exit(err)
+func Enter(ctx context.Context, resource string, opts ...EntryOption) (*Entry, error) {
+	return defaultGroup.Enter(ctx, resource, opts...)
 }
@@ -0,0 +1,127 @@
+package defender
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Entry 一次资源调用
+// 准入时创建，调用结束时 Exit，期间记录通过的治理组件
+type Entry struct {
+	opt      EntryOptions
+	ctx      context.Context
+	resource string
+	start    time.Time
+	rt       time.Duration
+	blockErr error
+	passed   []Defender
+	exited   int32
+	values   map[interface{}]interface{}
+}
+
+func newEntry(ctx context.Context, resource string, opts ...EntryOption) *Entry {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var opt EntryOptions
+	for _, o := range opts {
+		o(&opt)
+	}
+	return &Entry{
+		opt:      opt,
+		ctx:      ctx,
+		resource: resource,
+		start:    time.Now(),
+	}
+}
+
+// Context 调用上下文
+func (e *Entry) Context() context.Context {
+	return e.ctx
+}
+
+// Resource 资源名
+func (e *Entry) Resource() string {
+	return e.resource
+}
+
+// TrafficType 流量方向
+func (e *Entry) TrafficType() TrafficType {
+	return e.opt.trafficType
+}
+
+// Args 调用参数
+func (e *Entry) Args() []interface{} {
+	return e.opt.args
+}
+
+// StartTime 准入时间
+func (e *Entry) StartTime() time.Time {
+	return e.start
+}
+
+// RT 调用耗时，Exit 之后有效
+func (e *Entry) RT() time.Duration {
+	return e.rt
+}
+
+// BlockError 被拒绝时的错误，Exit 时用于区分拒绝与调用失败
+func (e *Entry) BlockError() error {
+	return e.blockErr
+}
+
+// SetValue 治理组件保存准入到结束之间的状态，如熔断的探测名额
+// key 建议使用组件自身，避免与其他组件冲突
+// Entry 只在准入与 Exit 时由调用方所在协程访问，不需要加锁
+func (e *Entry) SetValue(key, value interface{}) {
+	if e.values == nil {
+		e.values = make(map[interface{}]interface{})
+	}
+	e.values[key] = value
+}
+
+// Value 取出 SetValue 保存的状态
+func (e *Entry) Value(key interface{}) interface{} {
+	return e.values[key]
+}
+
+// Exit 调用结束，err 为业务调用结果
+// 重复调用只生效一次
+func (e *Entry) Exit(err error) {
+	if !atomic.CompareAndSwapInt32(&e.exited, 0, 1) {
+		return
+	}
+	e.rt = time.Since(e.start)
+	e.exit(err)
+}
+
+func (e *Entry) check(d Defender) (err error) {
+	if c, ok := d.(EntryChecker); ok {
+		err = c.CheckEntry(e)
+	} else {
+		err = d.Check(e.resource)
+	}
+	if err != nil {
+		return toBlockError(err, e.resource, d)
+	}
+	e.passed = append(e.passed, d)
+	return nil
+}
+
+// block 被后续组件拒绝，通知已经通过的组件释放资源
+func (e *Entry) block(err error) {
+	e.blockErr = err
+	atomic.StoreInt32(&e.exited, 1)
+	e.rt = time.Since(e.start)
+	e.exit(err)
+}
+
+// 逆序通知，与准入顺序对称
+func (e *Entry) exit(err error) {
+	for i := len(e.passed) - 1; i >= 0; i-- {
+		if h, ok := e.passed[i].(ExitHandler); ok {
+			h.Exit(e, err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package defender
+
+// TrafficType 流量方向
+type TrafficType int32
+
+const (
+	// Outbound 调用下游
+	Outbound TrafficType = iota
+	// Inbound 处理上游请求，系统保护只对入口流量生效
+	Inbound
+)
+
+type EntryOptions struct {
+	trafficType TrafficType
+	args        []interface{}
+}
+
+type EntryOption func(*EntryOptions)
+
+// WithTrafficType 流量方向，默认 Outbound
+func WithTrafficType(t TrafficType) EntryOption {
+	return func(o *EntryOptions) {
+		o.trafficType = t
+	}
+}
+
+// WithArgs 调用参数，用于热点参数流控
+func WithArgs(args ...interface{}) EntryOption {
+	return func(o *EntryOptions) {
+		o.args = args
+	}
+}
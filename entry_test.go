@@ -0,0 +1,60 @@
+package defender
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type exitDefender struct {
+	testDefender
+	exits *[]string
+}
+
+func (d *exitDefender) Exit(e *Entry, err error) {
+	*d.exits = append(*d.exits, d.name)
+}
+
+func TestEntry_ExitOrder(t *testing.T) {
+	var log, exits []string
+	g := NewGroup()
+	g.AddDefender(&exitDefender{testDefender{name: "flow", log: &log}, &exits}, 100)
+	g.AddDefender(&exitDefender{testDefender{name: "cb", log: &log}, &exits}, 200)
+	g.AddDefender(&exitDefender{testDefender{name: "isolation", log: &log}, &exits}, 300)
+
+	e, err := g.Enter(context.Background(), "res")
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+	e.Exit(nil)
+	e.Exit(nil)
+	if got := strings.Join(log, ","); got != "flow,cb,isolation" {
+		t.Errorf("expect check order flow,cb,isolation, but %v", got)
+	}
+	if got := strings.Join(exits, ","); got != "isolation,cb,flow" {
+		t.Errorf("expect exit order isolation,cb,flow, but %v", got)
+	}
+}
+
+func TestEntry_BlockReleasePassed(t *testing.T) {
+	var log, exits []string
+	errBlock := errors.New("block")
+	g := NewGroup()
+	g.AddDefender(&exitDefender{testDefender{name: "flow", log: &log}, &exits}, 100)
+	g.AddDefender(&exitDefender{testDefender{name: "cb", log: &log}, &exits}, 200)
+	g.AddDefender(&exitDefender{testDefender{name: "isolation", err: errBlock, log: &log}, &exits}, 300)
+	g.AddDefender(&exitDefender{testDefender{name: "last", log: &log}, &exits}, 400)
+
+	e, err := g.Enter(context.Background(), "res")
+	if e != nil || !errors.Is(err, errBlock) {
+		t.Fatalf("expect block %v, but %v", errBlock, err)
+	}
+	// 只有通过的组件收到 Exit，被拒绝的组件与后续组件不会收到
+	if got := strings.Join(exits, ","); got != "cb,flow" {
+		t.Errorf("expect exit cb,flow, but %v", got)
+	}
+	if got := strings.Join(log, ","); got != "flow,cb,isolation" {
+		t.Errorf("expect check flow,cb,isolation, but %v", got)
+	}
+}
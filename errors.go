@@ -0,0 +1,88 @@
+package defender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// BlockedErr 所有拒绝错误都满足 errors.Is(err, BlockedErr)
+	BlockedErr = errors.New("defender blocked")
+)
+
+// BlockError 请求被治理组件拒绝
+type BlockError struct {
+	Resource   string        // 资源名
+	Kind       string        // 拒绝的组件类型，如 circuitbreaker
+	RuleID     string        // 命中的规则
+	RetryAfter time.Duration // 建议的重试间隔，0 表示未知
+	Err        error         // 组件原始错误，如 circuitbreaker.CircuitBreakerOpenErr
+}
+
+func (e *BlockError) Error() string {
+	s := fmt.Sprintf("defender: resource %q blocked by %s", e.Resource, e.Kind)
+	if e.RuleID != "" {
+		s += fmt.Sprintf(" rule %s", e.RuleID)
+	}
+	if e.RetryAfter > 0 {
+		s += fmt.Sprintf(", retry after %v", e.RetryAfter)
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *BlockError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BlockError) Is(target error) bool {
+	return target == BlockedErr
+}
+
+// IsBlocked 是否为治理组件拒绝
+func IsBlocked(err error) bool {
+	return errors.Is(err, BlockedErr)
+}
+
+// AsBlockError 取出拒绝详情
+func AsBlockError(err error) (*BlockError, bool) {
+	var be *BlockError
+	if errors.As(err, &be) {
+		return be, true
+	}
+	return nil, false
+}
+
+// 组件返回的错误统一转换为 BlockError，补全资源名与组件类型
+// ctx 取消或超时是调用方放弃等待，不是拒绝，原样返回
+func toBlockError(err error, resource string, d Defender) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if be, ok := AsBlockError(err); ok {
+		if be.Resource != "" && be.Kind != "" {
+			return err
+		}
+		// 组件可能复用同一个错误值，补全字段时复制一份
+		cp := *be
+		if cp.Resource == "" {
+			cp.Resource = resource
+		}
+		if cp.Kind == "" {
+			cp.Kind = d.String()
+		}
+		return &cp
+	}
+	return &BlockError{
+		Resource: resource,
+		Kind:     d.String(),
+		Err:      err,
+	}
+}
@@ -0,0 +1,111 @@
+package flow
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// calculator 计算当前允许的 QPS 阈值
+type calculator interface {
+	threshold(nowMs int64, passQps float64) float64
+}
+
+func newCalculator(rule *Rule) calculator {
+	switch rule.TokenCalculateStrategy {
+	case WarmUp:
+		return newWarmUpCalculator(rule)
+	default:
+		return directCalculator(rule.Threshold)
+	}
+}
+
+type directCalculator float64
+
+func (c directCalculator) threshold(nowMs int64, passQps float64) float64 {
+	return float64(c)
+}
+
+// warmUpCalculator 冷启动，参考 Guava SmoothWarmingUp
+// 桶中存量令牌越多表示系统越冷，允许的 QPS 越低：
+// 存量超过 warningToken 时阈值沿斜率从 threshold 降到 threshold/coldFactor
+// 通过的请求消耗存量，系统逐渐变热，阈值逐渐恢复到 threshold
+//
+//	         ^ 1/QPS
+//	cold     |          /
+//	interval |         /.
+//	         |        / .
+//	         |       /  .
+//	stable   +------+   .
+//	interval |      .   .
+//	         +------+---+---> storedTokens
+//	         0   warning max
+type warmUpCalculator struct {
+	count        float64
+	coldFactor   float64
+	warningToken int64
+	maxToken     int64
+	slope        float64
+
+	storedTokens   int64
+	lastFilledTime int64
+}
+
+func newWarmUpCalculator(rule *Rule) *warmUpCalculator {
+	count := rule.Threshold
+	coldFactor := float64(rule.coldFactor())
+	period := float64(rule.WarmUpPeriodSec)
+
+	warningToken := int64(period * count / (coldFactor - 1))
+	maxToken := warningToken + int64(2*period*count/(1.0+coldFactor))
+	slope := (coldFactor - 1.0) / count / float64(maxToken-warningToken)
+	return &warmUpCalculator{
+		count:        count,
+		coldFactor:   coldFactor,
+		warningToken: warningToken,
+		maxToken:     maxToken,
+		slope:        slope,
+	}
+}
+
+func (c *warmUpCalculator) threshold(nowMs int64, passQps float64) float64 {
+	c.syncToken(nowMs, passQps)
+
+	restToken := atomic.LoadInt64(&c.storedTokens)
+	if restToken >= c.warningToken && c.maxToken > c.warningToken {
+		aboveToken := restToken - c.warningToken
+		return math.Nextafter(1.0/(float64(aboveToken)*c.slope+1.0/c.count), math.MaxFloat64)
+	}
+	return c.count
+}
+
+// syncToken 每秒补充一次令牌并扣除上一秒通过的请求
+func (c *warmUpCalculator) syncToken(nowMs int64, passQps float64) {
+	currentTime := nowMs - nowMs%1000
+	oldLastFillTime := atomic.LoadInt64(&c.lastFilledTime)
+	if currentTime <= oldLastFillTime {
+		return
+	}
+
+	oldValue := atomic.LoadInt64(&c.storedTokens)
+	newValue := c.coolDownTokens(currentTime, oldLastFillTime, oldValue, passQps)
+	if atomic.CompareAndSwapInt64(&c.storedTokens, oldValue, newValue) {
+		if atomic.AddInt64(&c.storedTokens, -int64(passQps)) < 0 {
+			atomic.StoreInt64(&c.storedTokens, 0)
+		}
+		atomic.StoreInt64(&c.lastFilledTime, currentTime)
+	}
+}
+
+func (c *warmUpCalculator) coolDownTokens(currentTime, lastFilledTime, oldValue int64, passQps float64) int64 {
+	newValue := oldValue
+	// 存量低于 warningToken 说明系统处于热状态，正常补充
+	// 存量高于 warningToken 时只有流量足够低才继续补充，即系统继续变冷
+	if oldValue < c.warningToken ||
+		(oldValue > c.warningToken && passQps < c.count/c.coldFactor) {
+		newValue = oldValue + int64(float64(currentTime-lastFilledTime)*c.count/1000)
+	}
+	if newValue > c.maxToken {
+		newValue = c.maxToken
+	}
+	return newValue
+}
@@ -0,0 +1,97 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// TokenStatus 集群令牌申请结果
+type TokenStatus int32
+
+const (
+	// TokenOK 通过
+	TokenOK TokenStatus = iota
+	// TokenBlocked 超过集群阈值
+	TokenBlocked
+	// TokenShouldWait 等待 Wait 后通过
+	TokenShouldWait
+)
+
+func (s TokenStatus) String() string {
+	switch s {
+	case TokenOK:
+		return "OK"
+	case TokenBlocked:
+		return "Blocked"
+	case TokenShouldWait:
+		return "ShouldWait"
+	default:
+		return fmt.Sprintf("TokenStatus(%d)", int32(s))
+	}
+}
+
+// TokenResult 集群令牌申请结果
+type TokenResult struct {
+	Status TokenStatus
+	Wait   time.Duration
+}
+
+// TokenService 集群令牌服务
+// 返回 error 表示服务不可用，流控退化为本地限流（调用方 ctx 结束导致的错误除外）
+type TokenService interface {
+	RequestToken(ctx context.Context, ruleID string, count int64) (TokenResult, error)
+}
+
+type tokenServiceHolder struct {
+	TokenService
+}
+
+var (
+	tokenService atomic.Value // tokenServiceHolder
+)
+
+// SetTokenService 设置集群令牌服务，nil 表示关闭集群限流，全部退化为本地限流
+func SetTokenService(s TokenService) {
+	tokenService.Store(tokenServiceHolder{s})
+}
+
+func currentTokenService() TokenService {
+	h, _ := tokenService.Load().(tokenServiceHolder)
+	return h.TokenService
+}
+
+// clusterCheck 向 token server 申请令牌
+// 服务不可用或超时返回 fallback 为 true，由调用方退化为本地限流
+// 调用方 ctx 取消或超时直接返回 ctx 的错误，不再退化
+func (c *Controller) clusterCheck(ctx context.Context) (fallback bool, err error) {
+	ts := currentTokenService()
+	if ts == nil {
+		return true, nil
+	}
+
+	result, err := ts.RequestToken(ctx, c.rule.id(), 1)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			return false, ctxErr
+		}
+		return true, nil
+	}
+	switch result.Status {
+	case TokenOK:
+		return false, nil
+	case TokenShouldWait:
+		t := time.NewTimer(result.Wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return false, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	default:
+		return false, c.blockError(result.Wait)
+	}
+}
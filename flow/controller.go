@@ -0,0 +1,187 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/internal/base"
+	"github.com/lanceryou/defender/internal/lru"
+)
+
+var (
+	FlowLimitErr = errors.New("flow limit exceeded")
+)
+
+// 排在系统保护之后、热点参数之前
+const defaultPriority = 100
+
+// LimitApp 为 other 时最多跟踪的调用方数量
+const maxOrigins = 1000
+
+// Controller 单条流控规则，实现 defender.Defender
+type Controller struct {
+	rule   Rule
+	shaper *shaper
+	// 集群模式下 token server 不可用时的本地配额
+	fallback *shaper
+
+	mu           sync.Mutex
+	origins      *lru.Cache // LimitApp 为 other 时每个调用方的配额
+	specificApps map[string]struct{}
+}
+
+func NewController(rule *Rule) (*Controller, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Controller{
+		rule: *rule,
+	}
+	if c.rule.limitApp() == LimitAppOther {
+		c.origins = lru.New(maxOrigins)
+	} else {
+		c.shaper = newShaper(&c.rule)
+	}
+	if c.rule.ClusterMode {
+		fallback := c.rule
+		fallback.Threshold = c.rule.clusterFallbackThreshold()
+		c.fallback = newShaper(&fallback)
+	}
+	return c, nil
+}
+
+// Rule 当前规则
+func (c *Controller) Rule() Rule {
+	return c.rule
+}
+
+// QPS 当前窗口的通过 QPS，LimitApp 为 other 时使用 OriginQPS
+func (c *Controller) QPS() float64 {
+	if c.shaper == nil {
+		return 0
+	}
+	return c.shaper.qps(base.UnixMs(time.Now()))
+}
+
+// OriginQPS 调用方当前窗口的通过 QPS
+func (c *Controller) OriginQPS(origin string) float64 {
+	s := c.shaperFor(origin)
+	if s == nil {
+		return 0
+	}
+	return s.qps(base.UnixMs(time.Now()))
+}
+
+// Threshold 当前生效的 QPS 阈值，冷启动时低于规则阈值
+func (c *Controller) Threshold() float64 {
+	if c.shaper == nil {
+		return c.rule.Threshold
+	}
+	return c.shaper.threshold(base.UnixMs(time.Now()))
+}
+
+// Pressure 实现 priority.PressureSource，当前 QPS 与阈值之比
+// LimitApp 为 other 或集群模式的规则没有本地汇总统计，返回 0
+func (c *Controller) Pressure() float64 {
+	if c.shaper == nil || c.rule.ClusterMode {
+		return 0
+	}
+	now := base.UnixMs(time.Now())
+	threshold := c.shaper.threshold(now)
+	if threshold <= 0 {
+		return 1
+	}
+	return c.shaper.qps(now) / threshold
+}
+
+// Check 实现 defender.Defender，没有调用方信息，排队等待不可取消
+func (c *Controller) Check(resource string) error {
+	return c.check(context.Background(), resource)
+}
+
+// CheckEntry 实现 defender.EntryChecker，调用方从 ctx 的 metadata 读取
+// 排队等待响应 ctx 取消
+func (c *Controller) CheckEntry(e *defender.Entry) error {
+	return c.check(e.Context(), e.Resource())
+}
+
+// TryAcquire 一次性占用 count 个令牌，不足时全部不占用并返回 *defender.BlockError
+// 不排队，不区分调用方，供 token server 按规则汇总计数
+func (c *Controller) TryAcquire(count int64) error {
+	if c.shaper == nil {
+		return nil
+	}
+	return c.shaper.tryAcquire(count)
+}
+
+func (c *Controller) blockError(retryAfter time.Duration) error {
+	return &defender.BlockError{
+		Resource:   c.rule.Resource,
+		Kind:       "flow",
+		RuleID:     c.rule.id(),
+		RetryAfter: retryAfter,
+		Err:        FlowLimitErr,
+	}
+}
+
+func (c *Controller) String() string {
+	return "flow:" + c.rule.id()
+}
+
+// Priority 实现 defender.Prioritized
+func (c *Controller) Priority() int {
+	return defaultPriority
+}
+
+// setSpecificApps 同资源其他规则单独指定的调用方，LimitApp 为 other 的规则跳过这些调用方
+func (c *Controller) setSpecificApps(apps map[string]struct{}) {
+	c.specificApps = apps
+}
+
+func (c *Controller) check(ctx context.Context, resource string) error {
+	if resource != c.rule.Resource {
+		return nil
+	}
+	if c.rule.ClusterMode {
+		if fallback, err := c.clusterCheck(ctx); !fallback {
+			return err
+		}
+		return c.fallback.check(ctx)
+	}
+	s := c.shaperFor(Origin(ctx))
+	if s == nil {
+		return nil
+	}
+	return s.check(ctx)
+}
+
+// shaperFor 返回调用方对应的配额，规则不适用于该调用方时返回 nil
+func (c *Controller) shaperFor(origin string) *shaper {
+	switch limitApp := c.rule.limitApp(); limitApp {
+	case LimitAppDefault:
+		return c.shaper
+	case LimitAppOther:
+		if origin == "" {
+			return nil
+		}
+		if _, ok := c.specificApps[origin]; ok {
+			return nil
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if s, ok := c.origins.Get(origin); ok {
+			return s.(*shaper)
+		}
+		s := newShaper(&c.rule)
+		c.origins.Add(origin, s)
+		return s
+	default:
+		if origin != limitApp {
+			return nil
+		}
+		return c.shaper
+	}
+}
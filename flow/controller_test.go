@@ -0,0 +1,262 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/pkg/metadata"
+)
+
+func TestController_Reject(t *testing.T) {
+	ts := []struct {
+		threshold float64
+		n         int
+		expect    int
+	}{
+		{threshold: 10, n: 20, expect: 10},
+		{threshold: 0, n: 5, expect: 0},
+		{threshold: 100, n: 50, expect: 50},
+	}
+
+	for _, s := range ts {
+		c, err := NewController(&Rule{Resource: "res", Threshold: s.threshold})
+		if err != nil {
+			t.Fatalf("unexpect err %v", err)
+		}
+		var pass int
+		for i := 0; i < s.n; i++ {
+			err := c.Check("res")
+			if err == nil {
+				pass++
+			} else if !errors.Is(err, FlowLimitErr) || !defender.IsBlocked(err) {
+				t.Fatalf("unexpect err %v", err)
+			}
+		}
+		if pass != s.expect {
+			t.Errorf("expect pass %v, but %v", s.expect, pass)
+		}
+	}
+}
+
+func TestController_Queue(t *testing.T) {
+	c, err := NewController(&Rule{
+		Resource:          "res",
+		Threshold:         10,
+		ControlBehavior:   Queue,
+		MaxQueueingTimeMs: 300,
+		StatIntervalMs:    100,
+		BucketCount:       2,
+	})
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+
+	// 每 100ms 窗口只允许 1 个请求，第二个请求需要排队等待窗口滑动
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := c.Check("res"); err != nil {
+			t.Fatalf("unexpect err %v", err)
+		}
+	}
+	if since := time.Since(now); since < 50*time.Millisecond {
+		t.Errorf("expect queueing, but cost %v", since)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e, err := defender.NewGroup().Enter(ctx, "res")
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+	if err := c.CheckEntry(e); err != context.Canceled {
+		t.Errorf("expect err %v, but %v", context.Canceled, err)
+	}
+}
+
+func TestController_WarmUp(t *testing.T) {
+	c, err := NewController(&Rule{
+		Resource:               "res",
+		Threshold:              90,
+		TokenCalculateStrategy: WarmUp,
+		WarmUpPeriodSec:        10,
+	})
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+
+	// 冷启动阈值为 Threshold/coldFactor
+	if threshold := c.Threshold(); threshold < 29 || threshold > 31 {
+		t.Errorf("expect cold threshold about 30, but %v", threshold)
+	}
+	var pass int
+	for i := 0; i < 90; i++ {
+		if c.Check("res") == nil {
+			pass++
+		}
+	}
+	if pass > 31 {
+		t.Errorf("expect pass about 30 when cold, but %v", pass)
+	}
+}
+
+func TestController_Throttling(t *testing.T) {
+	c, err := NewController(&Rule{
+		Resource:          "res",
+		Threshold:         100,
+		ControlBehavior:   Throttling,
+		MaxQueueingTimeMs: 25,
+	})
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+
+	// 10ms 放行一个，最多排队 25ms，同时到达的 5 个请求只有前 3 个能通过
+	now := time.Now()
+	var pass int64
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.Check("res") == nil {
+				atomic.AddInt64(&pass, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if pass != 3 {
+		t.Errorf("expect pass 3, but %v", pass)
+	}
+	if since := time.Since(now); since < 15*time.Millisecond {
+		t.Errorf("expect pacing about 20ms, but cost %v", since)
+	}
+}
+
+func TestThrottling_CoDel(t *testing.T) {
+	tc := newThrottlingChecker(&Rule{
+		Threshold:         100,
+		ControlBehavior:   Throttling,
+		MaxQueueingTimeMs: 1000,
+		CoDelTargetMs:     5,
+		CoDelIntervalMs:   10,
+	})
+
+	// 预计排队时长持续增长，两个观察窗口后进入过载
+	for i := 0; i < 20; i++ {
+		if _, _, ok := tc.reserve(100); !ok {
+			t.Fatalf("expect reserve %v pass", i)
+		}
+	}
+	time.Sleep(15 * time.Millisecond)
+	tc.reserve(100)
+	time.Sleep(15 * time.Millisecond)
+
+	latest := atomic.LoadInt64(&tc.latestPassedTime)
+	wait, _, ok := tc.reserve(100)
+	if ok || wait <= 10*time.Millisecond {
+		t.Errorf("expect dropped by codel, but ok %v wait %v", ok, wait)
+	}
+	// 丢弃的请求归还占用的间隔
+	if cur := atomic.LoadInt64(&tc.latestPassedTime); cur != latest {
+		t.Errorf("expect latest passed time %v, but %v", latest, cur)
+	}
+}
+
+func TestController_LimitApp(t *testing.T) {
+	rules := []*Rule{
+		{ID: "a", Resource: "res", LimitApp: "app-a", Threshold: 1},
+		{ID: "other", Resource: "res", LimitApp: LimitAppOther, Threshold: 2},
+	}
+	g := defender.NewGroup()
+	controllers := make([]*Controller, 0, len(rules))
+	apps := map[string]struct{}{"app-a": {}}
+	for _, r := range rules {
+		c, err := NewController(r)
+		if err != nil {
+			t.Fatalf("unexpect err %v", err)
+		}
+		c.setSpecificApps(apps)
+		controllers = append(controllers, c)
+		g.Register(c)
+	}
+
+	ts := []struct {
+		origin string
+		expect int
+	}{
+		{origin: "app-a", expect: 1},
+		{origin: "app-b", expect: 2},
+		{origin: "app-c", expect: 2},
+		// 没有调用方信息时不受这两条规则限制
+		{origin: "", expect: 5},
+	}
+	for _, s := range ts {
+		ctx := context.Background()
+		if s.origin != "" {
+			ctx = WithOrigin(ctx, s.origin)
+		}
+		var pass int
+		for i := 0; i < 5; i++ {
+			e, err := g.Enter(ctx, "res")
+			if err == nil {
+				pass++
+				e.Exit(nil)
+			}
+		}
+		if pass != s.expect {
+			t.Errorf("origin %q expect pass %v, but %v", s.origin, s.expect, pass)
+		}
+	}
+}
+
+func TestWithOrigin(t *testing.T) {
+	parent := metadata.NewMetadataFromContext(context.Background(), metadata.Pairs("trace", "t1"))
+	a := WithOrigin(parent, "app-a")
+	b := WithOrigin(parent, "app-b")
+
+	if origin := Origin(parent); origin != "" {
+		t.Errorf("expect parent origin empty, but %v", origin)
+	}
+	if Origin(a) != "app-a" || Origin(b) != "app-b" {
+		t.Errorf("expect app-a app-b, but %v %v", Origin(a), Origin(b))
+	}
+	if trace := metadata.FromContext(a).Get("trace"); trace != "t1" {
+		t.Errorf("expect inherit trace t1, but %v", trace)
+	}
+}
+
+type blockingTokenService struct{}
+
+func (blockingTokenService) RequestToken(ctx context.Context, ruleID string, count int64) (TokenResult, error) {
+	<-ctx.Done()
+	return TokenResult{}, ctx.Err()
+}
+
+func TestController_ClusterContextDone(t *testing.T) {
+	SetTokenService(blockingTokenService{})
+	defer SetTokenService(nil)
+
+	c, err := NewController(&Rule{
+		Resource:                 "res",
+		Threshold:                10,
+		ClusterMode:              true,
+		ClusterFallbackThreshold: 10,
+	})
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+	g := defender.NewGroup()
+	g.Register(c)
+
+	// 调用方超时不退化为本地限流
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.Enter(ctx, "res"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect err %v, but %v", context.DeadlineExceeded, err)
+	}
+}
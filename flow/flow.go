@@ -0,0 +1,48 @@
+package flow
+
+import (
+	"strings"
+
+	"github.com/lanceryou/defender"
+)
+
+// LoadRules 用 rules 整体替换全局注册表中的流控规则
+// 替换是原子的，其余治理组件不受影响
+func LoadRules(rules ...*Rule) error {
+	controllers := make([]*Controller, 0, len(rules))
+	for _, r := range rules {
+		c, err := NewController(r)
+		if err != nil {
+			return err
+		}
+		controllers = append(controllers, c)
+	}
+
+	// 每个资源单独指定的调用方
+	specificApps := make(map[string]map[string]struct{})
+	for _, c := range controllers {
+		limitApp := c.rule.limitApp()
+		if limitApp == LimitAppDefault || limitApp == LimitAppOther {
+			continue
+		}
+		if specificApps[c.rule.Resource] == nil {
+			specificApps[c.rule.Resource] = make(map[string]struct{})
+		}
+		specificApps[c.rule.Resource][limitApp] = struct{}{}
+	}
+	for _, c := range controllers {
+		c.setSpecificApps(specificApps[c.rule.Resource])
+	}
+
+	defender.Update(func(chain *defender.Chain) {
+		for _, d := range chain.Defenders() {
+			if strings.HasPrefix(d.String(), "flow:") {
+				chain.Remove(d.String())
+			}
+		}
+		for _, c := range controllers {
+			chain.Add(c, c.Priority())
+		}
+	})
+	return nil
+}
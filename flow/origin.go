@@ -0,0 +1,38 @@
+package flow
+
+import (
+	"context"
+
+	"github.com/lanceryou/defender/pkg/metadata"
+)
+
+const (
+	// LimitAppDefault 不区分调用方，所有调用方共享配额
+	LimitAppDefault = "default"
+	// LimitAppOther 没有被同资源其他规则单独指定的调用方，每个调用方各自一份配额
+	LimitAppOther = "other"
+)
+
+// OriginKey 调用方在 metadata 中的 key
+const OriginKey = "caller"
+
+// WithOrigin 设置调用方
+// ctx 已有 metadata 时在副本上修改，不影响父 ctx 与共享同一 metadata 的其他 ctx
+func WithOrigin(ctx context.Context, origin string) context.Context {
+	md := metadata.FromContext(ctx)
+	if md == nil {
+		return metadata.NewMetadataFromContext(ctx, metadata.Pairs(OriginKey, origin))
+	}
+	md = md.Copy()
+	md.Set(OriginKey, origin)
+	return metadata.NewMetadataFromContext(ctx, md)
+}
+
+// Origin 从 metadata 读取调用方，没有时为空
+func Origin(ctx context.Context) string {
+	md := metadata.FromContext(ctx)
+	if md == nil {
+		return ""
+	}
+	return md.Get(OriginKey)
+}
@@ -0,0 +1,167 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ControlBehavior 超过阈值后的流控效果
+type ControlBehavior int32
+
+const (
+	// Reject 直接拒绝
+	Reject ControlBehavior = iota
+	// Queue 排队等待窗口空出配额，最长等待 MaxQueueingTimeMs
+	Queue
+	// Throttling 匀速排队，请求按 1/Threshold 的间隔放行，排队超过 MaxQueueingTimeMs 的请求拒绝
+	// 适合不能承受突发流量的下游
+	Throttling
+)
+
+func (b ControlBehavior) String() string {
+	switch b {
+	case Reject:
+		return "Reject"
+	case Queue:
+		return "Queue"
+	case Throttling:
+		return "Throttling"
+	default:
+		return fmt.Sprintf("ControlBehavior(%d)", int32(b))
+	}
+}
+
+// TokenCalculateStrategy 阈值计算策略
+type TokenCalculateStrategy int32
+
+const (
+	// Direct 直接使用 Threshold
+	Direct TokenCalculateStrategy = iota
+	// WarmUp 冷启动，阈值在 WarmUpPeriodSec 内从 Threshold/WarmUpColdFactor 爬升到 Threshold
+	WarmUp
+)
+
+func (s TokenCalculateStrategy) String() string {
+	switch s {
+	case Direct:
+		return "Direct"
+	case WarmUp:
+		return "WarmUp"
+	default:
+		return fmt.Sprintf("TokenCalculateStrategy(%d)", int32(s))
+	}
+}
+
+const (
+	defaultStatIntervalMs = 1000
+	defaultBucketCount    = 10
+	defaultColdFactor     = 3
+)
+
+// Rule 流控规则
+type Rule struct {
+	// ID 规则标识，为空时使用 Resource
+	ID       string
+	Resource string
+	// LimitApp 针对的调用方：default（默认）所有调用方共享，指定调用方名只对该调用方生效，
+	// other 对其余调用方生效且每个调用方各自一份配额
+	LimitApp string
+	// Threshold 每秒允许通过的请求数
+	Threshold              float64
+	TokenCalculateStrategy TokenCalculateStrategy
+	ControlBehavior        ControlBehavior
+	// WarmUpPeriodSec 冷启动预热时长
+	WarmUpPeriodSec uint32
+	// WarmUpColdFactor 冷启动因子，冷启动时阈值为 Threshold/WarmUpColdFactor，默认 3
+	WarmUpColdFactor uint32
+	// MaxQueueingTimeMs 排队模式下的最长等待时间，Throttling 模式为 0 时表示不排队
+	MaxQueueingTimeMs int64
+	// StatIntervalMs 统计窗口时长，默认 1000ms
+	StatIntervalMs uint32
+	// BucketCount 统计窗口 bucket 数量，默认 10
+	BucketCount uint32
+
+	// CoDelTargetMs Throttling 模式下可接受的排队时长，0 表示不启用 CoDel
+	// 排队时长持续超过该值时进入过载状态，丢弃预计排队超过 2 倍该值的请求
+	CoDelTargetMs int64
+	// CoDelIntervalMs CoDel 观察窗口，默认 100ms
+	CoDelIntervalMs int64
+
+	// ClusterMode 集群限流，Threshold 为整个集群的阈值，由 token server 统一计数
+	ClusterMode bool
+	// ClusterFallbackThreshold token server 不可用时本地限流的阈值，默认 Threshold
+	ClusterFallbackThreshold float64
+}
+
+func (r *Rule) id() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Resource
+}
+
+func (r *Rule) limitApp() string {
+	if r.LimitApp == "" {
+		return LimitAppDefault
+	}
+	return r.LimitApp
+}
+
+func (r *Rule) clusterFallbackThreshold() float64 {
+	if r.ClusterFallbackThreshold <= 0 {
+		return r.Threshold
+	}
+	return r.ClusterFallbackThreshold
+}
+
+func (r *Rule) statIntervalMs() uint32 {
+	if r.StatIntervalMs == 0 {
+		return defaultStatIntervalMs
+	}
+	return r.StatIntervalMs
+}
+
+func (r *Rule) coldFactor() uint32 {
+	if r.WarmUpColdFactor == 0 {
+		return defaultColdFactor
+	}
+	return r.WarmUpColdFactor
+}
+
+func (r *Rule) bucketCount() uint32 {
+	if r.BucketCount == 0 {
+		return defaultBucketCount
+	}
+	return r.BucketCount
+}
+
+// Validate 校验规则
+func (r *Rule) Validate() error {
+	if r.Resource == "" {
+		return errors.New("flow: empty resource")
+	}
+	if r.Threshold < 0 {
+		return fmt.Errorf("flow: rule %s negative threshold %v", r.id(), r.Threshold)
+	}
+	if r.TokenCalculateStrategy == WarmUp {
+		if r.WarmUpPeriodSec == 0 {
+			return fmt.Errorf("flow: rule %s warm up needs positive WarmUpPeriodSec", r.id())
+		}
+		if r.coldFactor() <= 1 {
+			return fmt.Errorf("flow: rule %s WarmUpColdFactor must be greater than 1", r.id())
+		}
+	}
+	if r.ControlBehavior == Queue && r.MaxQueueingTimeMs <= 0 {
+		return fmt.Errorf("flow: rule %s queue behavior needs positive MaxQueueingTimeMs", r.id())
+	}
+	if r.MaxQueueingTimeMs < 0 {
+		return fmt.Errorf("flow: rule %s negative MaxQueueingTimeMs", r.id())
+	}
+	if r.ClusterMode && r.limitApp() != LimitAppDefault {
+		return fmt.Errorf("flow: rule %s cluster mode does not support LimitApp %s", r.id(), r.LimitApp)
+	}
+	if r.statIntervalMs()%r.bucketCount() != 0 {
+		return fmt.Errorf("flow: rule %s StatIntervalMs must be divided by BucketCount", r.id())
+	}
+	return nil
+}
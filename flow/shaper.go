@@ -0,0 +1,110 @@
+package flow
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/internal/base"
+)
+
+// shaper 一份流控配额：统计窗口，阈值计算与匀速排队状态
+// 按调用方限流时每个调用方一份
+type shaper struct {
+	rule       *Rule
+	window     *window
+	calculator calculator
+	throttling *throttlingChecker
+}
+
+func newShaper(rule *Rule) *shaper {
+	return &shaper{
+		rule:       rule,
+		window:     newWindow(rule.statIntervalMs(), rule.bucketCount()),
+		calculator: newCalculator(rule),
+		throttling: newThrottlingChecker(rule),
+	}
+}
+
+func (s *shaper) qps(nowMs int64) float64 {
+	pass := s.window.sum(nowMs)
+	return float64(pass) * 1000 / float64(s.rule.statIntervalMs())
+}
+
+func (s *shaper) threshold(nowMs int64) float64 {
+	return s.calculator.threshold(nowMs, s.qps(nowMs))
+}
+
+// 窗口内允许通过的请求数
+func (s *shaper) maxPass(nowMs int64) int64 {
+	return int64(math.Floor(s.threshold(nowMs) * float64(s.rule.statIntervalMs()) / 1000))
+}
+
+func (s *shaper) check(ctx context.Context) error {
+	if s.rule.ControlBehavior == Throttling {
+		return s.pacing(ctx)
+	}
+
+	start := time.Now()
+	for {
+		now := base.UnixMs(time.Now())
+		if s.window.tryAdd(now, 1, s.maxPass(now)) {
+			return nil
+		}
+
+		wait := time.Duration(s.window.nextExpire(now)-now) * time.Millisecond
+		if s.rule.ControlBehavior != Queue ||
+			time.Since(start)+wait > time.Duration(s.rule.MaxQueueingTimeMs)*time.Millisecond {
+			return s.blockError(wait)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// tryAcquire 一次性占用 n 个令牌，不足时全部不占用，不排队
+func (s *shaper) tryAcquire(n int64) error {
+	now := base.UnixMs(time.Now())
+	if s.window.tryAdd(now, n, s.maxPass(now)) {
+		return nil
+	}
+	return s.blockError(time.Duration(s.window.nextExpire(now)-now) * time.Millisecond)
+}
+
+// pacing 匀速排队，等待期间 ctx 取消时归还占用的间隔
+func (s *shaper) pacing(ctx context.Context) error {
+	wait, cost, ok := s.throttling.reserve(s.threshold(base.UnixMs(time.Now())))
+	if !ok {
+		return s.blockError(wait)
+	}
+
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			s.throttling.cancel(cost)
+			return ctx.Err()
+		}
+	}
+	s.window.add(base.UnixMs(time.Now()), 1)
+	return nil
+}
+
+func (s *shaper) blockError(retryAfter time.Duration) error {
+	return &defender.BlockError{
+		Resource:   s.rule.Resource,
+		Kind:       "flow",
+		RuleID:     s.rule.id(),
+		RetryAfter: retryAfter,
+		Err:        FlowLimitErr,
+	}
+}
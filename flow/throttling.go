@@ -0,0 +1,77 @@
+package flow
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lanceryou/defender/pkg/codel"
+)
+
+// throttlingChecker 匀速排队（漏桶）
+// 请求按 1/threshold 的固定间隔放行，需要排队超过 maxQueueing 的请求直接拒绝
+type throttlingChecker struct {
+	base             time.Time
+	maxQueueing      int64 // 纳秒
+	latestPassedTime int64 // 上一个请求的放行时间，相对 base 的纳秒数
+	codel            *codel.CoDel
+}
+
+func newThrottlingChecker(rule *Rule) *throttlingChecker {
+	var cd *codel.CoDel
+	if rule.CoDelTargetMs > 0 {
+		cd = codel.NewCoDel(time.Duration(rule.CoDelTargetMs)*time.Millisecond, time.Duration(rule.CoDelIntervalMs)*time.Millisecond)
+	}
+	return &throttlingChecker{
+		base:        time.Now(),
+		maxQueueing: rule.MaxQueueingTimeMs * int64(time.Millisecond),
+		// 保证第一个请求不需要等待
+		latestPassedTime: -int64(time.Hour),
+		codel:            cd,
+	}
+}
+
+// reserve 预定放行时间
+// 返回需要等待的时长与占用的间隔，ok 为 false 时 wait 为预计的排队时长
+func (t *throttlingChecker) reserve(threshold float64) (wait time.Duration, cost int64, ok bool) {
+	if threshold <= 0 {
+		return 0, 0, false
+	}
+	cost = int64(float64(time.Second) / threshold)
+	for {
+		now := int64(time.Since(t.base))
+		latest := atomic.LoadInt64(&t.latestPassedTime)
+		expected := latest + cost
+		if expected <= now {
+			// 已经空闲，立即放行
+			if atomic.CompareAndSwapInt64(&t.latestPassedTime, latest, now) {
+				// 无需排队的请求也要上报，CoDel 依赖低延迟样本退出过载状态
+				if t.codel != nil {
+					t.codel.Drop(0)
+				}
+				return 0, cost, true
+			}
+			continue
+		}
+
+		if expected-now > t.maxQueueing {
+			return time.Duration(expected - now), cost, false
+		}
+		passTime := atomic.AddInt64(&t.latestPassedTime, cost)
+		if passTime-now > t.maxQueueing {
+			atomic.AddInt64(&t.latestPassedTime, -cost)
+			return time.Duration(passTime - now), cost, false
+		}
+		wait = time.Duration(passTime - now)
+		// 匀速排队没有实际队列，用预计排队时长作为 CoDel 的排队时长
+		if t.codel != nil && t.codel.Drop(wait) {
+			atomic.AddInt64(&t.latestPassedTime, -cost)
+			return wait, cost, false
+		}
+		return wait, cost, true
+	}
+}
+
+// cancel 放弃排队，归还占用的间隔
+func (t *throttlingChecker) cancel(cost int64) {
+	atomic.AddInt64(&t.latestPassedTime, -cost)
+}
@@ -0,0 +1,78 @@
+package flow
+
+import (
+	"sync/atomic"
+
+	"github.com/lanceryou/defender/pkg/timering"
+)
+
+// 滑动窗口计数，与熔断统计共用 timering 的 bucket 语义
+type window struct {
+	passBuckets []passBucket
+	*timering.TimeRing
+}
+
+func newWindow(intervalInMs uint32, bucketCount uint32) *window {
+	w := &window{
+		passBuckets: make([]passBucket, bucketCount),
+	}
+
+	bucketResetArray := make([]timering.ResetBucket, len(w.passBuckets))
+	for i := 0; i < len(bucketResetArray); i++ {
+		bucketResetArray[i] = &w.passBuckets[i]
+	}
+	w.TimeRing = timering.NewTimeRing(intervalInMs, bucketCount)
+	w.SetResetBuckets(bucketResetArray)
+	return w
+}
+
+// add 计入当前 bucket
+func (w *window) add(nowMs int64, n int64) {
+	idx := w.CurrentIndex(nowMs)
+	atomic.AddInt64(&w.passBuckets[idx].pass, n)
+}
+
+// sum 窗口内的通过数
+func (w *window) sum(nowMs int64) int64 {
+	var cnt int64
+	for i := range w.passBuckets {
+		if w.IsValid(int64(i), nowMs) {
+			cnt += atomic.LoadInt64(&w.passBuckets[i].pass)
+		}
+	}
+	return cnt
+}
+
+// tryAdd 先计数再检查，超过 max 时回滚
+// 并发时宁可多拒绝也不超发
+func (w *window) tryAdd(nowMs int64, n int64, max int64) bool {
+	idx := w.CurrentIndex(nowMs)
+	atomic.AddInt64(&w.passBuckets[idx].pass, n)
+	if w.sum(nowMs) <= max {
+		return true
+	}
+	atomic.AddInt64(&w.passBuckets[idx].pass, -n)
+	return false
+}
+
+// nextExpire 最早一个有效 bucket 滑出窗口的时间
+func (w *window) nextExpire(nowMs int64) int64 {
+	next := nowMs + int64(w.IntervalInMs())
+	for i := range w.passBuckets {
+		if !w.IsValid(int64(i), nowMs) || atomic.LoadInt64(&w.passBuckets[i].pass) == 0 {
+			continue
+		}
+		if expire := w.BucketStart(int64(i)) + int64(w.IntervalInMs()); expire < next {
+			next = expire
+		}
+	}
+	return next
+}
+
+type passBucket struct {
+	pass int64 // 通过数
+}
+
+func (b *passBucket) Reset() {
+	atomic.StoreInt64(&b.pass, 0)
+}
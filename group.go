@@ -0,0 +1,111 @@
+package defender
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Group 一组治理组件，可以独立于全局注册表使用（测试，多租户）
+// 读路径无锁：修改时复制出新的 Chain 再原子替换，正在执行的检测不受影响
+type Group struct {
+	mu    sync.Mutex
+	chain atomic.Value // *Chain
+}
+
+func NewGroup() *Group {
+	g := &Group{}
+	g.chain.Store(NewChain())
+	return g
+}
+
+func (g *Group) load() *Chain {
+	return g.chain.Load().(*Chain)
+}
+
+// Update 在副本上批量修改后一次性发布
+func (g *Group) Update(fn func(c *Chain)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c := g.load().clone()
+	fn(c)
+	g.chain.Store(c)
+}
+
+// Register 按组件自身优先级注册，同名组件原子替换
+func (g *Group) Register(d Defender) {
+	g.AddDefender(d, priorityOf(d))
+}
+
+// AddDefender 按指定优先级注册，同名组件原子替换
+func (g *Group) AddDefender(d Defender, priority int) {
+	g.Update(func(c *Chain) {
+		c.Add(d, priority)
+	})
+}
+
+// InsertBefore 插入到 name 之前
+func (g *Group) InsertBefore(name string, d Defender) (ok bool) {
+	g.Update(func(c *Chain) {
+		ok = c.InsertBefore(name, d)
+	})
+	return
+}
+
+// InsertAfter 插入到 name 之后
+func (g *Group) InsertAfter(name string, d Defender) (ok bool) {
+	g.Update(func(c *Chain) {
+		ok = c.InsertAfter(name, d)
+	})
+	return
+}
+
+// Unregister 注销组件
+func (g *Group) Unregister(name string) (ok bool) {
+	g.Update(func(c *Chain) {
+		ok = c.Remove(name)
+	})
+	return
+}
+
+// Replace 用新的组件集合整体替换，用于配置重载
+func (g *Group) Replace(ds ...Defender) {
+	c := NewChain()
+	for _, d := range ds {
+		c.Add(d, priorityOf(d))
+	}
+
+	g.mu.Lock()
+	g.chain.Store(c)
+	g.mu.Unlock()
+}
+
+// Get 按名字查找组件
+func (g *Group) Get(name string) Defender {
+	return g.load().Get(name)
+}
+
+// List 按执行顺序返回当前组件
+func (g *Group) List() []Defender {
+	return g.load().Defenders()
+}
+
+// Check 只做准入判断
+func (g *Group) Check(resource string) error {
+	return g.load().Check(resource)
+}
+
+// CheckWithArgs 带调用参数的检测
+func (g *Group) CheckWithArgs(resource string, args ...interface{}) error {
+	return g.load().CheckWithArgs(resource, args...)
+}
+
+// Enter 资源准入，通过后调用方必须调用 Exit
+func (g *Group) Enter(ctx context.Context, resource string, opts ...EntryOption) (*Entry, error) {
+	e := newEntry(ctx, resource, opts...)
+	if err := g.load().Enter(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
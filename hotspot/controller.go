@@ -0,0 +1,128 @@
+package hotspot
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/internal/lru"
+	"github.com/lanceryou/defender/ratelimit"
+)
+
+var (
+	HotParamLimitErr = errors.New("hot param limit exceeded")
+)
+
+// 热点检查只针对单个资源，排在普通流控之后
+const defaultPriority = 150
+
+// Controller 单条热点参数规则，实现 defender.Defender
+// 每个参数值一个令牌桶，令牌桶保存在 LRU 中，冷门参数值会被淘汰
+type Controller struct {
+	rule Rule
+
+	mu      sync.Mutex
+	buckets *lru.Cache // 参数值 -> *ratelimit.TokenBucket
+}
+
+func NewController(rule *Rule) (*Controller, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	return &Controller{
+		rule:    *rule,
+		buckets: lru.New(rule.paramsMaxCapacity()),
+	}, nil
+}
+
+// Rule 当前规则
+func (c *Controller) Rule() Rule {
+	return c.rule
+}
+
+// Tracked 当前跟踪的参数值数量
+func (c *Controller) Tracked() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buckets.Len()
+}
+
+// Check 实现 defender.Defender，没有参数时不限制
+func (c *Controller) Check(resource string) error {
+	return nil
+}
+
+// CheckArgs 实现 defender.ArgsChecker
+func (c *Controller) CheckArgs(resource string, args []interface{}) error {
+	if resource != c.rule.Resource || c.rule.ParamIndex >= len(args) {
+		return nil
+	}
+	value := args[c.rule.ParamIndex]
+	if value == nil {
+		return nil
+	}
+
+	tb, ok := c.bucket(value)
+	if !ok {
+		return nil
+	}
+	if tb == nil || !tb.Allow() {
+		return c.blockError()
+	}
+	return nil
+}
+
+// CheckEntry 实现 defender.EntryChecker
+func (c *Controller) CheckEntry(e *defender.Entry) error {
+	return c.CheckArgs(e.Resource(), e.Args())
+}
+
+func (c *Controller) String() string {
+	return "hotspot:" + c.rule.id()
+}
+
+// Priority 实现 defender.Prioritized
+func (c *Controller) Priority() int {
+	return defaultPriority
+}
+
+// bucket 阈值为 0 的参数值返回 nil，表示全部拒绝
+// 参数不可比较时（含嵌套的 slice、map 等）无法作为 key，ok 为 false，不限制
+func (c *Controller) bucket(value interface{}) (_ *ratelimit.TokenBucket, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if tb, ok := c.buckets.Get(value); ok {
+		return tb.(*ratelimit.TokenBucket), true
+	}
+
+	threshold := c.rule.Threshold
+	if specific, ok := c.rule.SpecificItems[value]; ok {
+		threshold = specific
+	}
+	if threshold <= 0 {
+		return nil, true
+	}
+	burst := c.rule.BurstCount
+	if burst == 0 {
+		burst = int(math.Max(1, math.Ceil(threshold)))
+	}
+	tb := ratelimit.NewTokenBucket(threshold, burst)
+	c.buckets.Add(value, tb)
+	return tb, true
+}
+
+func (c *Controller) blockError() error {
+	return &defender.BlockError{
+		Resource: c.rule.Resource,
+		Kind:     "hotspot",
+		RuleID:   c.rule.id(),
+		Err:      HotParamLimitErr,
+	}
+}
@@ -0,0 +1,64 @@
+package hotspot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lanceryou/defender"
+)
+
+type argKey struct {
+	V interface{}
+}
+
+func TestController_CheckWithArgs(t *testing.T) {
+	c, err := NewController(&Rule{
+		Resource:          "res",
+		ParamIndex:        1,
+		Threshold:         2,
+		SpecificItems:     map[interface{}]float64{"vip": 5, "banned": 0},
+		ParamsMaxCapacity: 2,
+	})
+	if err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+	g := defender.NewGroup()
+	g.Register(c)
+
+	ts := []struct {
+		args   []interface{}
+		n      int
+		expect int
+	}{
+		{args: []interface{}{"a", "user1"}, n: 5, expect: 2},
+		{args: []interface{}{"a", "user2"}, n: 5, expect: 2},
+		{args: []interface{}{"a", "vip"}, n: 10, expect: 5},
+		{args: []interface{}{"a", "banned"}, n: 3, expect: 0},
+		// 参数不足或不可比较时不限制
+		{args: []interface{}{"a"}, n: 3, expect: 3},
+		{args: []interface{}{"a", []int{1}}, n: 3, expect: 3},
+		{args: []interface{}{"a", argKey{V: []int{1}}}, n: 3, expect: 3},
+	}
+
+	for _, s := range ts {
+		var pass int
+		for i := 0; i < s.n; i++ {
+			err := g.CheckWithArgs("res", s.args...)
+			if err == nil {
+				pass++
+			} else if !errors.Is(err, HotParamLimitErr) {
+				t.Fatalf("unexpect err %v", err)
+			}
+		}
+		if pass != s.expect {
+			t.Errorf("args %v expect pass %v, but %v", s.args, s.expect, pass)
+		}
+	}
+
+	if n := c.Tracked(); n != 2 {
+		t.Errorf("expect tracked 2, but %v", n)
+	}
+	if err := g.Check("res"); err != nil {
+		t.Errorf("check without args should pass, but %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package hotspot
+
+import (
+	"strings"
+
+	"github.com/lanceryou/defender"
+)
+
+// LoadRules 用 rules 整体替换全局注册表中的热点参数规则
+func LoadRules(rules ...*Rule) error {
+	controllers := make([]*Controller, 0, len(rules))
+	for _, r := range rules {
+		c, err := NewController(r)
+		if err != nil {
+			return err
+		}
+		controllers = append(controllers, c)
+	}
+
+	defender.Update(func(chain *defender.Chain) {
+		for _, d := range chain.Defenders() {
+			if strings.HasPrefix(d.String(), "hotspot:") {
+				chain.Remove(d.String())
+			}
+		}
+		for _, c := range controllers {
+			chain.Add(c, c.Priority())
+		}
+	})
+	return nil
+}
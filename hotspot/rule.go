@@ -0,0 +1,58 @@
+package hotspot
+
+import (
+	"errors"
+	"fmt"
+)
+
+const defaultParamsMaxCapacity = 4000
+
+// Rule 热点参数流控规则
+// 按第 ParamIndex 个参数的取值分别限流，每个取值每秒最多通过 Threshold 个请求
+type Rule struct {
+	// ID 规则标识，为空时使用 Resource
+	ID         string
+	Resource   string
+	ParamIndex int
+	// Threshold 每个参数值每秒允许通过的请求数
+	Threshold float64
+	// BurstCount 允许的突发请求数，默认等于 Threshold
+	BurstCount int
+	// SpecificItems 指定参数值的阈值，key 的类型需要与参数类型一致
+	SpecificItems map[interface{}]float64
+	// ParamsMaxCapacity 最多跟踪的参数值数量，按 LRU 淘汰，默认 4000
+	ParamsMaxCapacity int
+}
+
+func (r *Rule) id() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Resource
+}
+
+func (r *Rule) paramsMaxCapacity() int {
+	if r.ParamsMaxCapacity <= 0 {
+		return defaultParamsMaxCapacity
+	}
+	return r.ParamsMaxCapacity
+}
+
+// Validate 校验规则
+func (r *Rule) Validate() error {
+	if r.Resource == "" {
+		return errors.New("hotspot: empty resource")
+	}
+	if r.ParamIndex < 0 {
+		return fmt.Errorf("hotspot: rule %s negative ParamIndex %v", r.id(), r.ParamIndex)
+	}
+	if r.Threshold < 0 || r.BurstCount < 0 {
+		return fmt.Errorf("hotspot: rule %s negative threshold", r.id())
+	}
+	for k, v := range r.SpecificItems {
+		if v < 0 {
+			return fmt.Errorf("hotspot: rule %s negative threshold for %v", r.id(), k)
+		}
+	}
+	return nil
+}
@@ -12,16 +12,22 @@ type errorStat struct {
 	errBuckets []errBucket
 	ratio      float64
 	match      int64
+	minRequest int64 // 最小请求数
+	isFailure  func(err error) bool
 	*timering.TimeRing
 }
 
-func NewErrorStat(ring *timering.TimeRing, ratio float64, match int64) *errorStat {
+// NewErrorStat minRequest 窗口内请求数低于该值时不触发熔断
+// isFailure 判断非 nil 错误是否计为错误，nil 时全部计为错误
+func NewErrorStat(ring *timering.TimeRing, ratio float64, match int64, minRequest int64, isFailure func(err error) bool) *errorStat {
 	ss := &errorStat{
-		ratio: ratio,
-		match: match,
+		ratio:      ratio,
+		match:      match,
+		minRequest: minRequest,
+		isFailure:  isFailure,
 	}
 
-	// ss.errBuckets = make([]errBucket, intervalInMs/bucketCount)
+	ss.errBuckets = make([]errBucket, ring.BucketCount())
 	bucketResetArray := make([]timering.ResetBucket, len(ss.errBuckets))
 	for i := 0; i < len(bucketResetArray); i++ {
 		bucketResetArray[i] = &ss.errBuckets[i]
@@ -32,38 +38,45 @@ func NewErrorStat(ring *timering.TimeRing, ratio float64, match int64) *errorSta
 }
 
 func (s *errorStat) MatchCount() int64 {
-	var cnt int64
-	for _, bucket := range s.errBuckets {
-		cnt += bucket.errCount
-	}
-
-	return cnt
+	return s.sum(base.UnixMs(time.Now()), func(b *errBucket) int64 {
+		return atomic.LoadInt64(&b.errCount)
+	})
 }
 
 func (s *errorStat) Total() int64 {
-	var cnt int64
-	for _, bucket := range s.errBuckets {
-		cnt += bucket.totalCount
-	}
-
-	return cnt
+	return s.sum(base.UnixMs(time.Now()), func(b *errBucket) int64 {
+		return atomic.LoadInt64(&b.totalCount)
+	})
 }
 
 func (s *errorStat) Stat(fn func() error, cr func(match bool, reach bool)) func() error {
 	return func() error {
+		start := time.Now()
 		err := fn()
-		idx := s.CurrentIndex(time.Now().UnixNano())
-		match := err != nil
-		if match {
-			atomic.AddInt64(&s.errBuckets[idx].errCount, 1)
-		}
-		atomic.AddInt64(&s.errBuckets[idx].totalCount, 1)
-
-		cr(match, s.reachCircuit())
+		s.Record(err, time.Since(start), cr)
 		return err
 	}
 }
 
+// Record 上报一次调用结果，供两阶段接口在调用结束后使用
+func (s *errorStat) Record(err error, rt time.Duration, cr func(match bool, reach bool)) {
+	idx := s.CurrentIndex(base.UnixMs(time.Now()))
+	match := err != nil && (s.isFailure == nil || s.isFailure(err))
+	if match {
+		atomic.AddInt64(&s.errBuckets[idx].errCount, 1)
+	}
+	atomic.AddInt64(&s.errBuckets[idx].totalCount, 1)
+
+	cr(match, s.reachCircuit())
+}
+
+// Reset 清空窗口内的统计
+func (s *errorStat) Reset() {
+	for i := range s.errBuckets {
+		s.errBuckets[i].Reset()
+	}
+}
+
 func (s *errorStat) String() string {
 	return "errStat"
 }
@@ -71,10 +84,24 @@ func (s *errorStat) String() string {
 func (s *errorStat) reachCircuit() bool {
 	matchCount := s.MatchCount()
 	totalCount := s.Total()
-	return matchCount >= s.match ||
+	if totalCount == 0 || totalCount < s.minRequest {
+		return false
+	}
+	return (s.match > 0 && matchCount >= s.match) ||
 		base.FloatGte(float64(matchCount)/float64(totalCount), s.ratio)
 }
 
+// sum 汇总窗口内未过期 bucket 的计数
+func (s *errorStat) sum(now int64, get func(b *errBucket) int64) int64 {
+	var cnt int64
+	for i := range s.errBuckets {
+		if s.IsValid(int64(i), now) {
+			cnt += get(&s.errBuckets[i])
+		}
+	}
+	return cnt
+}
+
 type errBucket struct {
 	errCount   int64 // 错误总数
 	totalCount int64 // 请求数
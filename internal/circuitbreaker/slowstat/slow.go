@@ -11,18 +11,23 @@ import (
 type SlowStat struct {
 	slowResponseMs int64 // 慢回复值
 	ratio          float64
+	minRequest     int64 // 最小请求数
+	isFailure      func(err error) bool
 	slowBuckets    []SlowBucket
 	*timering.TimeRing
 }
 
-func NewSlowStat(slowResponseMs int64, ring *timering.TimeRing, ratio float64) *SlowStat {
+// NewSlowStat minRequest 窗口内请求数低于该值时不触发熔断
+// isFailure 判断非 nil 错误是否为故障，不是故障的错误（业务错误）不计为慢调用
+func NewSlowStat(slowResponseMs int64, ring *timering.TimeRing, ratio float64, minRequest int64, isFailure func(err error) bool) *SlowStat {
 	ss := &SlowStat{
 		slowResponseMs: slowResponseMs,
 		ratio:          ratio,
+		minRequest:     minRequest,
+		isFailure:      isFailure,
 	}
 
-	// ss.slowBuckets = make([]SlowBucket, intervalInMs/bucketCount)
-
+	ss.slowBuckets = make([]SlowBucket, ring.BucketCount())
 	bucketResetArray := make([]timering.ResetBucket, len(ss.slowBuckets))
 	for i := 0; i < len(bucketResetArray); i++ {
 		bucketResetArray[i] = &ss.slowBuckets[i]
@@ -33,39 +38,45 @@ func NewSlowStat(slowResponseMs int64, ring *timering.TimeRing, ratio float64) *
 }
 
 func (s *SlowStat) MatchCount() int64 {
-	var cnt int64
-	for _, bucket := range s.slowBuckets {
-		cnt += bucket.slowCount
-	}
-
-	return cnt
+	return s.sum(base.UnixMs(time.Now()), func(b *SlowBucket) int64 {
+		return atomic.LoadInt64(&b.slowCount)
+	})
 }
 
 func (s *SlowStat) Total() int64 {
-	var cnt int64
-	for _, bucket := range s.slowBuckets {
-		cnt += bucket.totalCount
-	}
-
-	return cnt
+	return s.sum(base.UnixMs(time.Now()), func(b *SlowBucket) int64 {
+		return atomic.LoadInt64(&b.totalCount)
+	})
 }
 
 func (s *SlowStat) Stat(fn func() error, cr func(match bool, reach bool)) func() error {
 	return func() error {
-		start := time.Now().UnixNano()
+		start := time.Now()
 		err := fn()
-		end := time.Now().UnixNano()
-		elapsed := end - start
+		s.Record(err, time.Since(start), cr)
+		return err
+	}
+}
 
-		idx := s.CurrentIndex(start)
-		match := elapsed >= s.slowResponseMs
-		if match {
-			atomic.AddInt64(&s.slowBuckets[idx].slowCount, 1)
-		}
-		atomic.AddInt64(&s.slowBuckets[idx].totalCount, 1)
+// Record 上报一次调用结果，供两阶段接口在调用结束后使用
+func (s *SlowStat) Record(err error, rt time.Duration, cr func(match bool, reach bool)) {
+	idx := s.CurrentIndex(base.UnixMs(time.Now()))
+	match := rt >= time.Duration(s.slowResponseMs)*time.Millisecond
+	if match && err != nil && s.isFailure != nil && !s.isFailure(err) {
+		match = false
+	}
+	if match {
+		atomic.AddInt64(&s.slowBuckets[idx].slowCount, 1)
+	}
+	atomic.AddInt64(&s.slowBuckets[idx].totalCount, 1)
 
-		cr(match, s.ratioDetect())
-		return err
+	cr(match, s.ratioDetect())
+}
+
+// Reset 清空窗口内的统计
+func (s *SlowStat) Reset() {
+	for i := range s.slowBuckets {
+		s.slowBuckets[i].Reset()
 	}
 }
 
@@ -74,11 +85,25 @@ func (s *SlowStat) String() string {
 }
 
 func (s *SlowStat) ratioDetect() bool {
-	return base.FloatGte(float64(s.MatchCount())/float64(s.Total()), s.ratio)
+	total := s.Total()
+	if total == 0 || total < s.minRequest {
+		return false
+	}
+	return base.FloatGte(float64(s.MatchCount())/float64(total), s.ratio)
+}
+
+// sum 汇总窗口内未过期 bucket 的计数
+func (s *SlowStat) sum(now int64, get func(b *SlowBucket) int64) int64 {
+	var cnt int64
+	for i := range s.slowBuckets {
+		if s.IsValid(int64(i), now) {
+			cnt += get(&s.slowBuckets[i])
+		}
+	}
+	return cnt
 }
 
-//
-// 慢回复统计
+// SlowBucket 慢回复统计
 // 获取当前桶，在桶里统计计数
 type SlowBucket struct {
 	slowCount  int64 // 慢回复总数
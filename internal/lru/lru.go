@@ -0,0 +1,55 @@
+package lru
+
+import (
+	"container/list"
+)
+
+// Cache 固定容量的 LRU，非并发安全
+type Cache struct {
+	capacity int
+	ll       *list.List
+	items    map[interface{}]*list.Element
+}
+
+type entry struct {
+	key   interface{}
+	value interface{}
+}
+
+func New(capacity int) *Cache {
+	return &Cache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[interface{}]*list.Element),
+	}
+}
+
+// Get 读取并标记为最近使用
+func (c *Cache) Get(key interface{}) (interface{}, bool) {
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		return el.Value.(*entry).value, true
+	}
+	return nil, false
+}
+
+// Add 写入，超过容量时淘汰最久未使用的 key
+func (c *Cache) Add(key interface{}, value interface{}) {
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*entry).value = value
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&entry{key: key, value: value})
+	if c.capacity > 0 && c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+	}
+}
+
+// Len 当前数量
+func (c *Cache) Len() int {
+	return c.ll.Len()
+}
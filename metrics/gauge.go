@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// Gauge 瞬时值指标
+type Gauge struct {
+	Name  string
+	Value float64
+}
+
+// GaugeSource 提供一组瞬时值指标，如系统 CPU、内存采样
+type GaugeSource interface {
+	Gauges() []Gauge
+}
+
+var (
+	mu      sync.RWMutex
+	sources = make(map[string]GaugeSource)
+)
+
+// RegisterGaugeSource 注册指标来源，同名覆盖
+func RegisterGaugeSource(name string, src GaugeSource) {
+	mu.Lock()
+	defer mu.Unlock()
+	sources[name] = src
+}
+
+// UnregisterGaugeSource 注销指标来源
+func UnregisterGaugeSource(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(sources, name)
+}
+
+// Gather 按来源名顺序汇总所有指标的当前值
+func Gather() []Gauge {
+	mu.RLock()
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	srcs := make([]GaugeSource, 0, len(sources))
+	sort.Strings(names)
+	for _, name := range names {
+		srcs = append(srcs, sources[name])
+	}
+	mu.RUnlock()
+
+	var gauges []Gauge
+	for _, src := range srcs {
+		gauges = append(gauges, src.Gauges()...)
+	}
+	return gauges
+}
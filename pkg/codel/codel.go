@@ -0,0 +1,72 @@
+package codel
+
+import (
+	"sync"
+	"time"
+)
+
+// CoDel 受控延迟（Controlled Delay）
+// 出队时上报排队时长，一个 interval 内的最小排队时长超过 target 说明队列持续积压，进入过载状态
+// 过载时排队超过 2*target 的请求已经大概率无用，直接丢弃
+type CoDel struct {
+	target   time.Duration
+	interval time.Duration
+	now      func() time.Time
+
+	mu          sync.Mutex
+	intervalEnd time.Time
+	minDelay    time.Duration
+	overloaded  bool
+}
+
+// DefaultInterval 未指定观察窗口时的默认值
+const DefaultInterval = 100 * time.Millisecond
+
+// NewCoDel target 可接受的排队时长，interval 观察窗口，不大于 0 时使用 DefaultInterval
+func NewCoDel(target, interval time.Duration) *CoDel {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &CoDel{
+		target:   target,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Observe 上报排队时长，更新过载状态，返回是否过载
+// 队列应当上报队首（排队最久）元素的排队时长，过载时按后进先出出队的元素排队时长接近 0，不能反映积压
+func (c *CoDel) Observe(sojourn time.Duration) bool {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.After(c.intervalEnd) {
+		// 窗口结束，按窗口内最小排队时长判断是否过载
+		if !c.intervalEnd.IsZero() {
+			c.overloaded = c.minDelay > c.target
+		}
+		c.minDelay = sojourn
+		c.intervalEnd = now.Add(c.interval)
+	} else if sojourn < c.minDelay {
+		c.minDelay = sojourn
+	}
+	return c.overloaded
+}
+
+// Drop 先进先出出队时调用，上报排队时长并返回该请求是否应当丢弃
+func (c *CoDel) Drop(sojourn time.Duration) bool {
+	return c.Observe(sojourn) && c.Expired(sojourn)
+}
+
+// Expired 排队时长超过 2*target，过载时应当丢弃
+func (c *CoDel) Expired(sojourn time.Duration) bool {
+	return sojourn > 2*c.target
+}
+
+// Overloaded 是否处于过载状态
+func (c *CoDel) Overloaded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.overloaded
+}
@@ -0,0 +1,95 @@
+package codel
+
+import (
+	"time"
+)
+
+type item struct {
+	value   interface{}
+	enqueue time.Time
+}
+
+// Queue 带 CoDel 的队列，非并发安全，由调用方加锁
+// 正常时先进先出；过载时后进先出，优先服务刚到达、仍然有用的请求，并丢弃排队过久的请求
+// codel 为 nil 时退化为普通 FIFO
+type Queue struct {
+	codel *CoDel
+	items []item
+	now   func() time.Time
+}
+
+func NewQueue(codel *CoDel) *Queue {
+	return &Queue{
+		codel: codel,
+		now:   time.Now,
+	}
+}
+
+// Push 入队
+func (q *Queue) Push(v interface{}) {
+	q.items = append(q.items, item{value: v, enqueue: q.now()})
+}
+
+// Pop 出队，dropped 为本次出队过程中被丢弃的元素，由调用方通知拒绝
+// 每次出队上报队首的排队时长；过载时先丢弃队首排队超过 2*target 的元素，再取最新的元素
+func (q *Queue) Pop() (v interface{}, dropped []interface{}, ok bool) {
+	if len(q.items) == 0 {
+		return nil, nil, false
+	}
+	if q.codel == nil {
+		return q.popFront().value, nil, true
+	}
+
+	now := q.now()
+	if !q.codel.Observe(now.Sub(q.items[0].enqueue)) {
+		return q.popFront().value, nil, true
+	}
+	for len(q.items) > 0 && q.codel.Expired(now.Sub(q.items[0].enqueue)) {
+		dropped = append(dropped, q.popFront().value)
+	}
+	if len(q.items) == 0 {
+		return nil, dropped, false
+	}
+	return q.popBack().value, dropped, true
+}
+
+// Remove 移除指定元素，用于排队期间取消的请求，不存在时返回 false
+func (q *Queue) Remove(v interface{}) bool {
+	for i := range q.items {
+		if q.items[i].value == v {
+			copy(q.items[i:], q.items[i+1:])
+			q.items[len(q.items)-1] = item{}
+			q.items = q.items[:len(q.items)-1]
+			return true
+		}
+	}
+	return false
+}
+
+// PopOldest 取出排队最久的元素，不经过 CoDel 判断
+func (q *Queue) PopOldest() (interface{}, bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	return q.popFront().value, true
+}
+
+// Len 队列长度
+func (q *Queue) Len() int {
+	return len(q.items)
+}
+
+func (q *Queue) popFront() item {
+	it := q.items[0]
+	q.items[0] = item{}
+	q.items = q.items[1:]
+	return it
+}
+
+func (q *Queue) popBack() item {
+	n := len(q.items) - 1
+	it := q.items[n]
+	q.items[n] = item{}
+	q.items = q.items[:n]
+	return it
+}
@@ -0,0 +1,89 @@
+package codel
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestQueue(target, interval time.Duration) (*Queue, *time.Time) {
+	now := time.Unix(0, 0)
+	clock := func() time.Time {
+		return now
+	}
+	cd := NewCoDel(target, interval)
+	cd.now = clock
+	q := NewQueue(cd)
+	q.now = clock
+	return q, &now
+}
+
+func TestQueue_SustainedOverload(t *testing.T) {
+	target := 10 * time.Millisecond
+	q, now := newTestQueue(target, 100*time.Millisecond)
+
+	// 到达速率是处理速率的两倍
+	var flips, stale, pops int
+	overloaded := false
+	for i := 0; i < 3000; i++ {
+		*now = now.Add(time.Millisecond)
+		q.Push(*now)
+		q.Push(*now)
+		v, _, ok := q.Pop()
+		if !ok {
+			continue
+		}
+		if q.codel.Overloaded() != overloaded {
+			overloaded = !overloaded
+			flips++
+		}
+		if overloaded {
+			pops++
+			if now.Sub(v.(time.Time)) > 2*target {
+				stale++
+			}
+		}
+	}
+	if flips != 1 {
+		t.Errorf("expect overload once and keep, but flips %v", flips)
+	}
+	if pops == 0 || stale != 0 {
+		t.Errorf("expect no stale item served while overloaded, but %v/%v", stale, pops)
+	}
+	if q.Len() > int(4*target/time.Millisecond) {
+		t.Errorf("expect expired items dropped, but queue len %v", q.Len())
+	}
+
+	// 负载回落后恢复先进先出
+	for i := 0; i < 300; i++ {
+		*now = now.Add(time.Millisecond)
+		q.Push(*now)
+		q.Pop()
+		q.Pop()
+	}
+	if q.codel.Overloaded() {
+		t.Errorf("expect overload cleared")
+	}
+	q.Push(1)
+	q.Push(2)
+	if v, _, _ := q.Pop(); v != 1 {
+		t.Errorf("expect fifo, but %v", v)
+	}
+}
+
+func TestQueue_Remove(t *testing.T) {
+	q := NewQueue(nil)
+	for i := 0; i < 3; i++ {
+		q.Push(i)
+	}
+	if !q.Remove(1) || q.Remove(1) {
+		t.Fatalf("remove should succeed only once")
+	}
+	for _, expect := range []int{0, 2} {
+		if v, _, _ := q.Pop(); v != expect {
+			t.Errorf("expect %v, but %v", expect, v)
+		}
+	}
+	if q.Len() != 0 {
+		t.Errorf("expect empty, but %v", q.Len())
+	}
+}
@@ -0,0 +1,305 @@
+package sampler
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// 超过该值的内存限制视为不限制（cgroup v1 未设置时为 9223372036854771712）
+const unlimitedMemory = 1 << 62
+
+// 内核 USER_HZ，/proc/stat 的单位
+const userHZ = 100
+
+// resource 读取累计 CPU 时间与内存占用
+type resource interface {
+	// cpuUsage 累计 CPU 时间，纳秒
+	cpuUsage() (uint64, error)
+	// cpuLimit 可用核数，0 表示不限制
+	cpuLimit() (float64, error)
+	memUsage() (uint64, error)
+	// memLimit 内存上限，0 表示不限制
+	memLimit() (uint64, error)
+	String() string
+}
+
+// detect 按 cgroup v2，cgroup v1，宿主机的顺序探测
+// 进程所在的 cgroup 从 /proc/self/cgroup 读取，没有 cgroup namespace 时（systemd 服务等）挂载点根目录是宿主机的 cgroup
+func detect(root string) resource {
+	cgroupRoot := filepath.Join(root, "sys/fs/cgroup")
+	paths := selfCgroups(filepath.Join(root, "proc/self/cgroup"))
+	if exists(filepath.Join(cgroupRoot, "cgroup.controllers")) {
+		return &cgroupV2{dir: cgroupDir(cgroupRoot, paths[""])}
+	}
+	for _, cpuacct := range []string{"cpuacct", "cpu,cpuacct"} {
+		if exists(filepath.Join(cgroupRoot, cpuacct, "cpuacct.usage")) {
+			cpu := cpuacct
+			if cpu == "cpuacct" {
+				cpu = "cpu"
+			}
+			return &cgroupV1{
+				cpuacct: cgroupDir(filepath.Join(cgroupRoot, cpuacct), paths["cpuacct"]),
+				cpu:     cgroupDir(filepath.Join(cgroupRoot, cpu), paths["cpu"]),
+				memory:  cgroupDir(filepath.Join(cgroupRoot, "memory"), paths["memory"]),
+			}
+		}
+	}
+	return &host{dir: filepath.Join(root, "proc")}
+}
+
+// selfCgroups 解析 /proc/self/cgroup，返回控制器到 cgroup 路径的映射，cgroup v2 的控制器为 ""
+//
+//	0::/system.slice/app.service
+//	4:cpu,cpuacct:/docker/abc
+func selfCgroups(path string) map[string]string {
+	paths := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil {
+		return paths
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths
+}
+
+// cgroupDir 挂载点下进程所在的 cgroup 目录
+// 有 cgroup namespace 时路径为 /，容器内看不到宿主机路径时退回挂载点
+func cgroupDir(mount string, path string) string {
+	if path == "" || path == "/" {
+		return mount
+	}
+	dir := filepath.Join(mount, path)
+	if !exists(dir) {
+		return mount
+	}
+	return dir
+}
+
+// cgroupV2 统一层级
+type cgroupV2 struct {
+	dir string
+}
+
+func (c *cgroupV2) cpuUsage() (uint64, error) {
+	// usage_usec 1234
+	v, err := readKeyValue(filepath.Join(c.dir, "cpu.stat"), "usage_usec")
+	return v * 1000, err
+}
+
+func (c *cgroupV2) cpuLimit() (float64, error) {
+	// max 100000 或 200000 100000
+	fields, err := readFields(filepath.Join(c.dir, "cpu.max"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if len(fields) != 2 {
+		return 0, errors.New("sampler: invalid cpu.max")
+	}
+	if fields[0] == "max" {
+		return 0, nil
+	}
+	return parseQuota(fields[0], fields[1])
+}
+
+func (c *cgroupV2) memUsage() (uint64, error) {
+	return readUint(filepath.Join(c.dir, "memory.current"))
+}
+
+func (c *cgroupV2) memLimit() (uint64, error) {
+	fields, err := readFields(filepath.Join(c.dir, "memory.max"))
+	if err != nil || len(fields) == 0 || fields[0] == "max" {
+		return 0, err
+	}
+	return normalizeMemLimit(strconv.ParseUint(fields[0], 10, 64))
+}
+
+func (c *cgroupV2) String() string {
+	return "cgroup2"
+}
+
+// cgroupV1 各控制器独立挂载，记录每个控制器下进程所在的目录
+type cgroupV1 struct {
+	cpuacct string
+	cpu     string
+	memory  string
+}
+
+func (c *cgroupV1) cpuUsage() (uint64, error) {
+	return readUint(filepath.Join(c.cpuacct, "cpuacct.usage"))
+}
+
+func (c *cgroupV1) cpuLimit() (float64, error) {
+	quota, err := readFields(filepath.Join(c.cpu, "cpu.cfs_quota_us"))
+	if err != nil || len(quota) == 0 || quota[0] == "-1" {
+		return 0, ignoreNotExist(err)
+	}
+	period, err := readFields(filepath.Join(c.cpu, "cpu.cfs_period_us"))
+	if err != nil || len(period) == 0 {
+		return 0, ignoreNotExist(err)
+	}
+	return parseQuota(quota[0], period[0])
+}
+
+func (c *cgroupV1) memUsage() (uint64, error) {
+	return readUint(filepath.Join(c.memory, "memory.usage_in_bytes"))
+}
+
+func (c *cgroupV1) memLimit() (uint64, error) {
+	return normalizeMemLimit(readUint(filepath.Join(c.memory, "memory.limit_in_bytes")))
+}
+
+func (c *cgroupV1) String() string {
+	return "cgroup1"
+}
+
+// host 非容器环境读取 /proc
+type host struct {
+	dir string
+}
+
+func (h *host) cpuUsage() (uint64, error) {
+	// cpu user nice system idle iowait irq softirq steal
+	fields, err := readFields(filepath.Join(h.dir, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, errors.New("sampler: invalid /proc/stat")
+	}
+	var busy uint64
+	for i, field := range fields[1:] {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if i != 3 && i != 4 {
+			busy += v
+		}
+	}
+	return busy * (1e9 / userHZ), nil
+}
+
+func (h *host) cpuLimit() (float64, error) {
+	return 0, nil
+}
+
+func (h *host) memUsage() (uint64, error) {
+	total, err := readKeyValue(filepath.Join(h.dir, "meminfo"), "MemTotal:")
+	if err != nil {
+		return 0, err
+	}
+	available, err := readKeyValue(filepath.Join(h.dir, "meminfo"), "MemAvailable:")
+	if err != nil {
+		return 0, err
+	}
+	return (total - available) * 1024, nil
+}
+
+func (h *host) memLimit() (uint64, error) {
+	total, err := readKeyValue(filepath.Join(h.dir, "meminfo"), "MemTotal:")
+	return total * 1024, err
+}
+
+func (h *host) String() string {
+	return "host"
+}
+
+func hostCPUs() float64 {
+	return float64(runtime.NumCPU())
+}
+
+func parseQuota(quota, period string) (float64, error) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, err
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p == 0 {
+		return 0, errors.New("sampler: invalid cpu period")
+	}
+	return q / p, nil
+}
+
+func normalizeMemLimit(limit uint64, err error) (uint64, error) {
+	if err != nil || limit >= unlimitedMemory {
+		return 0, err
+	}
+	return limit, nil
+}
+
+func ignoreNotExist(err error) error {
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// readFields 读取第一行并按空白分割
+func readFields(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	return strings.Fields(scanner.Text()), nil
+}
+
+func readUint(path string) (uint64, error) {
+	fields, err := readFields(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) == 0 {
+		return 0, errors.New("sampler: empty " + path)
+	}
+	return strconv.ParseUint(fields[0], 10, 64)
+}
+
+// readKeyValue 读取 "key value" 格式文件中 key 对应的值
+func readKeyValue(path string, key string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("sampler: " + key + " not found in " + path)
+}
@@ -0,0 +1,34 @@
+package sampler
+
+import (
+	"time"
+)
+
+type Options struct {
+	root     string
+	interval time.Duration
+	decay    float64
+}
+
+type Option func(*Options)
+
+// WithRoot 文件系统根目录，默认 /，测试时指向伪造的目录
+func WithRoot(root string) Option {
+	return func(o *Options) {
+		o.root = root
+	}
+}
+
+// WithInterval 采样间隔，默认 250ms
+func WithInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.interval = interval
+	}
+}
+
+// WithDecay EWMA 衰减系数，越大越平滑，默认 0.95
+func WithDecay(decay float64) Option {
+	return func(o *Options) {
+		o.decay = decay
+	}
+}
@@ -0,0 +1,183 @@
+package sampler
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lanceryou/defender/metrics"
+)
+
+// Stats 采样结果，CPU 与内存使用均经过 EWMA 平滑
+type Stats struct {
+	// CPU 使用率，千分比，相对 CPULimit
+	CPU int64
+	// CPULimit 可用核数，容器内为 cgroup 配额，否则为机器核数
+	CPULimit float64
+	// MemoryUsage 内存占用，字节
+	MemoryUsage uint64
+	// MemoryLimit 内存上限，字节，0 表示未知
+	MemoryLimit uint64
+}
+
+// MemoryRatio 内存使用率，千分比，上限未知时为 0
+func (s Stats) MemoryRatio() int64 {
+	if s.MemoryLimit == 0 {
+		return 0
+	}
+	return int64(float64(s.MemoryUsage) / float64(s.MemoryLimit) * 1000)
+}
+
+// Sampler 周期采样 CPU 与内存
+// 容器内读取 cgroup v1/v2，否则读取 /proc
+type Sampler struct {
+	opt   Options
+	res   resource
+	now   func() time.Time
+	stats atomic.Value // Stats
+
+	mu        sync.Mutex
+	lastUsage uint64
+	lastTime  time.Time
+	cpu       *ewma
+	mem       *ewma
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func New(opts ...Option) *Sampler {
+	opt := Options{
+		root:     "/",
+		interval: 250 * time.Millisecond,
+		decay:    0.95,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	s := &Sampler{
+		opt:  opt,
+		res:  detect(opt.root),
+		now:  time.Now,
+		cpu:  &ewma{decay: opt.decay},
+		mem:  &ewma{decay: opt.decay},
+		stop: make(chan struct{}),
+	}
+	s.stats.Store(Stats{})
+	return s
+}
+
+// Source 数据来源：cgroup2，cgroup1 或 host
+func (s *Sampler) Source() string {
+	return s.res.String()
+}
+
+// Stats 最近一次采样结果
+func (s *Sampler) Stats() Stats {
+	return s.stats.Load().(Stats)
+}
+
+// Gauges 实现 metrics.GaugeSource
+func (s *Sampler) Gauges() []metrics.Gauge {
+	stats := s.Stats()
+	return []metrics.Gauge{
+		{Name: "system_cpu_usage", Value: float64(stats.CPU)},
+		{Name: "system_cpu_limit", Value: stats.CPULimit},
+		{Name: "system_memory_usage", Value: float64(stats.MemoryUsage)},
+		{Name: "system_memory_limit", Value: float64(stats.MemoryLimit)},
+	}
+}
+
+// Start 启动后台采样
+func (s *Sampler) Start() {
+	s.Sample()
+	go func() {
+		ticker := time.NewTicker(s.opt.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Sample()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台采样
+func (s *Sampler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Sample 采样一次，读取失败的指标保持上一次的值
+func (s *Sampler) Sample() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.Stats()
+	now := s.now()
+
+	limit, err := s.res.cpuLimit()
+	if err != nil || limit <= 0 {
+		limit = hostCPUs()
+	}
+	stats.CPULimit = limit
+
+	if usage, err := s.res.cpuUsage(); err == nil {
+		if !s.lastTime.IsZero() && usage >= s.lastUsage && now.After(s.lastTime) {
+			wall := float64(now.Sub(s.lastTime))
+			cur := float64(usage-s.lastUsage) / (wall * limit) * 1000
+			if cur > 1000 {
+				cur = 1000
+			}
+			stats.CPU = int64(s.cpu.add(cur))
+		}
+		s.lastUsage, s.lastTime = usage, now
+	}
+
+	if usage, err := s.res.memUsage(); err == nil {
+		stats.MemoryUsage = uint64(s.mem.add(float64(usage)))
+	}
+	if limit, err := s.res.memLimit(); err == nil {
+		stats.MemoryLimit = limit
+	}
+
+	s.stats.Store(stats)
+	return stats
+}
+
+// ewma 指数加权平均，第一个采样直接作为初始值
+type ewma struct {
+	decay   float64
+	value   float64
+	sampled bool
+}
+
+func (e *ewma) add(v float64) float64 {
+	if !e.sampled {
+		e.sampled = true
+		e.value = v
+	} else {
+		e.value = e.value*e.decay + v*(1-e.decay)
+	}
+	return e.value
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultSampler *Sampler
+)
+
+// Default 全局采样器，首次调用时启动并注册到 metrics
+func Default() *Sampler {
+	defaultOnce.Do(func() {
+		defaultSampler = New()
+		defaultSampler.Start()
+		metrics.RegisterGaugeSource("sampler", defaultSampler)
+	})
+	return defaultSampler
+}
@@ -0,0 +1,117 @@
+package sampler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSampler_Sample(t *testing.T) {
+	ts := []struct {
+		source string
+		before map[string]string
+		after  map[string]string
+		stats  Stats
+	}{
+		{
+			source: "cgroup2",
+			before: map[string]string{
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory",
+				"sys/fs/cgroup/cpu.max":            "200000 100000",
+				"sys/fs/cgroup/cpu.stat":           "usage_usec 1000000\nuser_usec 800000\n",
+				"sys/fs/cgroup/memory.current":     "536870912",
+				"sys/fs/cgroup/memory.max":         "1073741824",
+			},
+			// 1s 内用了 1s CPU，配额 2 核，使用率 50%
+			after: map[string]string{
+				"sys/fs/cgroup/cpu.stat": "usage_usec 2000000\nuser_usec 1600000\n",
+			},
+			stats: Stats{CPU: 500, CPULimit: 2, MemoryUsage: 536870912, MemoryLimit: 1073741824},
+		},
+		{
+			source: "cgroup1",
+			before: map[string]string{
+				"sys/fs/cgroup/cpuacct/cpuacct.usage":        "1000000000",
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "50000",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000",
+				"sys/fs/cgroup/memory/memory.usage_in_bytes": "1024",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712",
+			},
+			// 1s 内用了 0.25s CPU，配额 0.5 核，使用率 50%
+			after: map[string]string{
+				"sys/fs/cgroup/cpuacct/cpuacct.usage": "1250000000",
+			},
+			stats: Stats{CPU: 500, CPULimit: 0.5, MemoryUsage: 1024},
+		},
+		{
+			// 没有 cgroup namespace，挂载点根目录是宿主机的 cgroup
+			source: "cgroup2",
+			before: map[string]string{
+				"proc/self/cgroup":                                      "0::/system.slice/app.service\n",
+				"sys/fs/cgroup/cgroup.controllers":                      "cpu memory",
+				"sys/fs/cgroup/cpu.stat":                                "usage_usec 9000000\n",
+				"sys/fs/cgroup/system.slice/app.service/cpu.max":        "100000 100000",
+				"sys/fs/cgroup/system.slice/app.service/cpu.stat":       "usage_usec 1000000\n",
+				"sys/fs/cgroup/system.slice/app.service/memory.current": "1024",
+				"sys/fs/cgroup/system.slice/app.service/memory.max":     "4096",
+			},
+			after: map[string]string{
+				"sys/fs/cgroup/system.slice/app.service/cpu.stat": "usage_usec 1500000\n",
+			},
+			stats: Stats{CPU: 500, CPULimit: 1, MemoryUsage: 1024, MemoryLimit: 4096},
+		},
+		{
+			source: "cgroup1",
+			before: map[string]string{
+				"proc/self/cgroup":                                "4:cpu,cpuacct:/app\n3:memory:/app\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpuacct.usage":         "9000000000",
+				"sys/fs/cgroup/cpu,cpuacct/app/cpuacct.usage":     "1000000000",
+				"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_quota_us":  "200000",
+				"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_period_us": "100000",
+				"sys/fs/cgroup/memory/app/memory.usage_in_bytes":  "2048",
+				"sys/fs/cgroup/memory/app/memory.limit_in_bytes":  "8192",
+			},
+			after: map[string]string{
+				"sys/fs/cgroup/cpu,cpuacct/app/cpuacct.usage": "2000000000",
+			},
+			stats: Stats{CPU: 500, CPULimit: 2, MemoryUsage: 2048, MemoryLimit: 8192},
+		},
+	}
+
+	for _, s := range ts {
+		root := t.TempDir()
+		writeFiles(t, root, s.before)
+
+		now := time.Now()
+		sp := New(WithRoot(root))
+		sp.now = func() time.Time {
+			return now
+		}
+		if sp.Source() != s.source {
+			t.Fatalf("expect source %v, but %v", s.source, sp.Source())
+		}
+		sp.Sample()
+
+		writeFiles(t, root, s.after)
+		now = now.Add(time.Second)
+		if stats := sp.Sample(); stats != s.stats {
+			t.Errorf("expect stats %+v, but %+v", s.stats, stats)
+		}
+		if g := sp.Gauges(); g[0].Name != "system_cpu_usage" || g[0].Value != float64(s.stats.CPU) {
+			t.Errorf("expect cpu gauge %v, but %+v", s.stats.CPU, g[0])
+		}
+	}
+}
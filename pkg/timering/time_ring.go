@@ -61,7 +61,7 @@ func (r *TimeRing) CurrentIndex(timeMills int64) int64 {
 	bucketStart := calculateStartTime(timeMills, r.bucketLengthInMs)
 
 	for {
-		bucket := r.buckets[idx]
+		bucket := &r.buckets[idx]
 		// first enter
 		startTime := atomic.LoadInt64(&bucket.startTime)
 		if startTime == 0 {
@@ -84,12 +84,34 @@ func (r *TimeRing) CurrentIndex(timeMills int64) int64 {
 				runtime.Gosched()
 			}
 		} else {
-			// something error, why happened
-			panic(fmt.Errorf("startTime %v > bucketStart %v", startTime, bucketStart))
+			// 并发时取时间较早的调用方落后于已经滚动的 bucket，计入当前 bucket
+			return idx
 		}
 	}
 }
 
+// BucketCount bucket 数量
+func (r *TimeRing) BucketCount() uint32 {
+	return r.bucketCount
+}
+
+// IntervalInMs 窗口时长
+func (r *TimeRing) IntervalInMs() uint32 {
+	return r.intervalInMs
+}
+
+// BucketStart bucket 开始时间，0 表示从未使用
+func (r *TimeRing) BucketStart(idx int64) int64 {
+	return atomic.LoadInt64(&r.buckets[idx].startTime)
+}
+
+// IsValid bucket 是否仍在以 timeMills 结尾的窗口内
+// 环上过期未 reset 的 bucket 统计时需要跳过
+func (r *TimeRing) IsValid(idx int64, timeMills int64) bool {
+	startTime := r.BucketStart(idx)
+	return startTime != 0 && startTime <= timeMills && timeMills-startTime < int64(r.intervalInMs)
+}
+
 func calculateStartTime(now int64, bucketLengthInMs uint32) int64 {
 	return now - (now % int64(bucketLengthInMs))
 }
@@ -0,0 +1,101 @@
+package priority
+
+import (
+	"errors"
+
+	"github.com/lanceryou/defender"
+)
+
+var (
+	PriorityShedErr = errors.New("low priority request shed")
+)
+
+// PressureSource 负载压力，0 表示空闲，1 表示达到阈值
+// flow.Controller，system.BBR，system.LoadShedder 均实现了该接口
+type PressureSource interface {
+	Pressure() float64
+}
+
+// PressureFunc 函数形式的压力来源
+type PressureFunc func() float64
+
+func (f PressureFunc) Pressure() float64 {
+	return f()
+}
+
+// Admission 按优先级准入
+// 压力升高时从低到高逐级丢弃，压力回落后自动恢复
+type Admission struct {
+	opt Options
+}
+
+func NewAdmission(opts ...Option) *Admission {
+	opt := Options{
+		priority:  defaultPriority,
+		threshold: defaultThreshold(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &Admission{
+		opt: opt,
+	}
+}
+
+// Pressure 当前压力，所有来源的最大值
+func (a *Admission) Pressure() float64 {
+	var max float64
+	for _, s := range a.opt.sources {
+		if p := s.Pressure(); p > max {
+			max = p
+		}
+	}
+	return max
+}
+
+// Allow 当前压力下优先级为 p 的请求是否允许通过
+func (a *Admission) Allow(p Priority) bool {
+	threshold, ok := a.opt.threshold[p]
+	if !ok {
+		return true
+	}
+	return a.Pressure() < threshold
+}
+
+// Check 实现 defender.Defender，没有上下文时按 Normal 处理
+func (a *Admission) Check(resource string) error {
+	return a.check(resource, Normal)
+}
+
+// CheckEntry 实现 defender.EntryChecker，优先级从 ctx 读取
+func (a *Admission) CheckEntry(e *defender.Entry) error {
+	return a.check(e.Resource(), FromContext(e.Context()))
+}
+
+func (a *Admission) String() string {
+	if a.opt.resource == "" {
+		return "priority"
+	}
+	return "priority:" + a.opt.resource
+}
+
+// Priority 实现 defender.Prioritized
+func (a *Admission) Priority() int {
+	return a.opt.priority
+}
+
+func (a *Admission) check(resource string, p Priority) error {
+	if a.opt.resource != "" && a.opt.resource != resource {
+		return nil
+	}
+	if a.Allow(p) {
+		return nil
+	}
+	return &defender.BlockError{
+		Resource: resource,
+		Kind:     "priority",
+		RuleID:   p.String(),
+		Err:      PriorityShedErr,
+	}
+}
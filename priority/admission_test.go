@@ -0,0 +1,50 @@
+package priority
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/pkg/metadata"
+)
+
+func TestAdmission_Shed(t *testing.T) {
+	var pressure float64
+	g := defender.NewGroup()
+	g.Register(NewAdmission(WithPressureSource(PressureFunc(func() float64 {
+		return pressure
+	}))))
+
+	ctxs := []context.Context{
+		NewContext(context.Background(), Low),
+		context.Background(),
+		metadata.NewMetadataFromContext(context.Background(), metadata.Pairs(MetadataKey, "high")),
+		NewContext(context.Background(), Critical),
+	}
+
+	ts := []struct {
+		pressure float64
+		// 依次为 Low Normal High Critical 是否通过
+		expect [4]bool
+	}{
+		{pressure: 0.5, expect: [4]bool{true, true, true, true}},
+		{pressure: 0.8, expect: [4]bool{false, true, true, true}},
+		{pressure: 0.9, expect: [4]bool{false, false, true, true}},
+		{pressure: 2, expect: [4]bool{false, false, false, true}},
+	}
+	for _, s := range ts {
+		pressure = s.pressure
+		for i, ctx := range ctxs {
+			e, err := g.Enter(ctx, "res")
+			if err == nil {
+				e.Exit(nil)
+			} else if !errors.Is(err, PriorityShedErr) {
+				t.Fatalf("unexpect err %v", err)
+			}
+			if pass := err == nil; pass != s.expect[i] {
+				t.Errorf("pressure %v priority %v expect pass %v, but %v", s.pressure, Priority(i), s.expect[i], pass)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package priority
+
+import (
+	"math"
+)
+
+// 按优先级丢弃排在流控与系统保护之前，低优先级请求不占用它们的配额
+const defaultPriority = 40
+
+type Options struct {
+	resource  string
+	priority  int
+	sources   []PressureSource
+	threshold map[Priority]float64
+}
+
+type Option func(*Options)
+
+// 默认压力达到 70% 开始丢弃 Low，85% 丢弃 Normal，95% 丢弃 High，Critical 不丢弃
+func defaultThreshold() map[Priority]float64 {
+	return map[Priority]float64{
+		Low:      0.7,
+		Normal:   0.85,
+		High:     0.95,
+		Critical: math.Inf(1),
+	}
+}
+
+// WithResource 生效的资源名，为空表示对所有资源生效
+func WithResource(resource string) Option {
+	return func(o *Options) {
+		o.resource = resource
+	}
+}
+
+// WithPriority 在治理链中的优先级
+func WithPriority(priority int) Option {
+	return func(o *Options) {
+		o.priority = priority
+	}
+}
+
+// WithPressureSource 压力来源，取所有来源的最大值
+func WithPressureSource(sources ...PressureSource) Option {
+	return func(o *Options) {
+		o.sources = append(o.sources, sources...)
+	}
+}
+
+// WithShedThreshold 压力达到 pressure 时开始丢弃优先级为 p 的请求
+func WithShedThreshold(p Priority, pressure float64) Option {
+	return func(o *Options) {
+		o.threshold[p] = pressure
+	}
+}
@@ -0,0 +1,78 @@
+package priority
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lanceryou/defender/pkg/metadata"
+)
+
+// Priority 请求优先级，数值越大越重要，过载时先丢弃低优先级请求
+type Priority int32
+
+const (
+	Low Priority = iota
+	Normal
+	High
+	Critical
+)
+
+func (p Priority) String() string {
+	switch p {
+	case Low:
+		return "low"
+	case Normal:
+		return "normal"
+	case High:
+		return "high"
+	case Critical:
+		return "critical"
+	default:
+		return fmt.Sprintf("Priority(%d)", int32(p))
+	}
+}
+
+// Parse 解析优先级名称或数字
+func Parse(s string) (Priority, bool) {
+	switch strings.ToLower(s) {
+	case "low":
+		return Low, true
+	case "normal":
+		return Normal, true
+	case "high":
+		return High, true
+	case "critical":
+		return Critical, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < int(Low) || n > int(Critical) {
+		return Normal, false
+	}
+	return Priority(n), true
+}
+
+// MetadataKey 优先级在 metadata 中的 key，通常由上游随请求传递
+const MetadataKey = "priority"
+
+type priorityKey struct{}
+
+// NewContext 设置本进程内的请求优先级
+func NewContext(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// FromContext 读取请求优先级
+// 优先使用 NewContext 设置的值，其次是 metadata，都没有时为 Normal
+func FromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	if md := metadata.FromContext(ctx); md != nil {
+		if p, ok := Parse(md.Get(MetadataKey)); ok {
+			return p
+		}
+	}
+	return Normal
+}
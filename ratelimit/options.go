@@ -0,0 +1,25 @@
+package ratelimit
+
+// 与 flow 规则同档
+const defaultPriority = 100
+
+type Options struct {
+	resource string
+	priority int
+}
+
+type Option func(*Options)
+
+// WithResource 限流的资源名，为空表示对所有资源生效
+func WithResource(resource string) Option {
+	return func(o *Options) {
+		o.resource = resource
+	}
+}
+
+// WithPriority 在治理链中的优先级
+func WithPriority(priority int) Option {
+	return func(o *Options) {
+		o.priority = priority
+	}
+}
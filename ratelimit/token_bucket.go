@@ -0,0 +1,209 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/lanceryou/defender"
+)
+
+var (
+	RateLimitErr   = errors.New("rate limit exceeded")
+	WaitTimeoutErr = errors.New("rate limit wait exceed context deadline")
+)
+
+// 令牌桶限流
+// 使用 GCRA 实现：只维护下一个令牌的理论到达时间(tat)，CAS 更新，无锁
+// tat - now 表示已经透支的令牌，超过 burst 个令牌的时长即拒绝
+type TokenBucket struct {
+	opt       Options
+	base      time.Time
+	interval  int64 // 生成一个令牌的纳秒数
+	tolerance int64 // burst 个令牌的纳秒数
+	burst     int
+	tat       int64 // 相对 base 的纳秒数
+}
+
+// NewTokenBucket rate 每秒生成的令牌数，burst 桶容量
+// rate 为 math.Inf(1) 时不限流
+func NewTokenBucket(rate float64, burst int, opts ...Option) *TokenBucket {
+	if rate <= 0 || burst <= 0 {
+		panic(errors.New("ratelimit: rate and burst must be positive"))
+	}
+	opt := Options{
+		priority: defaultPriority,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	var interval int64
+	if !math.IsInf(rate, 1) {
+		interval = int64(float64(time.Second) / rate)
+	}
+	return &TokenBucket{
+		opt:       opt,
+		base:      time.Now(),
+		interval:  interval,
+		tolerance: interval * int64(burst),
+		burst:     burst,
+	}
+}
+
+func (b *TokenBucket) now() int64 {
+	return int64(time.Since(b.base))
+}
+
+// Allow 获取一个令牌
+func (b *TokenBucket) Allow() bool {
+	return b.AllowN(1)
+}
+
+// AllowN 获取 n 个令牌，不足时立即返回 false
+func (b *TokenBucket) AllowN(n int) bool {
+	if n > b.burst {
+		return false
+	}
+	for {
+		tat := atomic.LoadInt64(&b.tat)
+		now := b.now()
+		newTat := maxInt64(tat, now) + int64(n)*b.interval
+		if newTat-now > b.tolerance {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&b.tat, tat, newTat) {
+			return true
+		}
+	}
+}
+
+// Reserve 预定一个令牌
+func (b *TokenBucket) Reserve() *Reservation {
+	return b.ReserveN(1)
+}
+
+// ReserveN 预定 n 个令牌，令牌不足时透支，调用方按 Delay 等待后再执行
+// n 超过桶容量时返回 OK() 为 false 的预定
+func (b *TokenBucket) ReserveN(n int) *Reservation {
+	if n > b.burst {
+		return &Reservation{}
+	}
+	for {
+		tat := atomic.LoadInt64(&b.tat)
+		now := b.now()
+		newTat := maxInt64(tat, now) + int64(n)*b.interval
+		if atomic.CompareAndSwapInt64(&b.tat, tat, newTat) {
+			return &Reservation{
+				ok:     true,
+				tb:     b,
+				tokens: n,
+				delay:  time.Duration(maxInt64(newTat-now-b.tolerance, 0)),
+			}
+		}
+	}
+}
+
+// Wait 等待一个令牌
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return b.WaitN(ctx, 1)
+}
+
+// WaitN 等待 n 个令牌，ctx 取消或等待超过 ctx 截止时间时归还令牌并返回错误
+func (b *TokenBucket) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := b.ReserveN(n)
+	if !r.OK() {
+		return RateLimitErr
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		r.Cancel()
+		return WaitTimeoutErr
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// Check 实现 defender.Defender
+func (b *TokenBucket) Check(resource string) error {
+	if b.opt.resource != "" && b.opt.resource != resource {
+		return nil
+	}
+	if b.Allow() {
+		return nil
+	}
+	return &defender.BlockError{
+		Resource:   resource,
+		Kind:       "ratelimit",
+		RetryAfter: b.retryAfter(),
+		Err:        RateLimitErr,
+	}
+}
+
+// 下一个令牌可用的时间
+func (b *TokenBucket) retryAfter() time.Duration {
+	tat := atomic.LoadInt64(&b.tat)
+	return time.Duration(maxInt64(tat-b.tolerance+b.interval-b.now(), 0))
+}
+
+func (b *TokenBucket) String() string {
+	if b.opt.resource == "" {
+		return "ratelimit"
+	}
+	return "ratelimit:" + b.opt.resource
+}
+
+// Priority 实现 defender.Prioritized
+func (b *TokenBucket) Priority() int {
+	return b.opt.priority
+}
+
+// Reservation 令牌预定
+type Reservation struct {
+	ok     bool
+	tb     *TokenBucket
+	tokens int
+	delay  time.Duration
+}
+
+// OK 是否预定成功
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay 需要等待的时间
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel 放弃预定并归还令牌，尽力而为
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+	r.ok = false
+	atomic.AddInt64(&r.tb.tat, -int64(r.tokens)*r.tb.interval)
+}
+
+func maxInt64(l, r int64) int64 {
+	if l > r {
+		return l
+	}
+	return r
+}
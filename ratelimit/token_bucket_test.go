@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lanceryou/defender"
+)
+
+func TestTokenBucket_Allow(t *testing.T) {
+	ts := []struct {
+		rate   float64
+		burst  int
+		n      int
+		expect int
+	}{
+		{rate: 10, burst: 1, n: 5, expect: 1},
+		{rate: 10, burst: 5, n: 10, expect: 5},
+		{rate: 10, burst: 100, n: 50, expect: 50},
+	}
+
+	for _, s := range ts {
+		tb := NewTokenBucket(s.rate, s.burst)
+		var pass int
+		for i := 0; i < s.n; i++ {
+			if tb.Allow() {
+				pass++
+			}
+		}
+		if pass != s.expect {
+			t.Errorf("expect pass %v, but %v", s.expect, pass)
+		}
+	}
+}
+
+func TestTokenBucket_Wait(t *testing.T) {
+	tb := NewTokenBucket(100, 1)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := tb.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpect err %v", err)
+		}
+	}
+	if since := time.Since(now); since < 15*time.Millisecond {
+		t.Errorf("expect wait about 20ms, but %v", since)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	tb = NewTokenBucket(1, 1)
+	tb.Allow()
+	if err := tb.Wait(ctx); err != WaitTimeoutErr {
+		t.Errorf("expect err %v, but %v", WaitTimeoutErr, err)
+	}
+}
+
+func TestTokenBucket_Check(t *testing.T) {
+	tb := NewTokenBucket(1, 1, WithResource("res"))
+	if err := tb.Check("res"); err != nil {
+		t.Fatalf("unexpect err %v", err)
+	}
+	if err := tb.Check("other"); err != nil {
+		t.Errorf("other resource should not be limited, but %v", err)
+	}
+	err := tb.Check("res")
+	if !errors.Is(err, RateLimitErr) || !defender.IsBlocked(err) {
+		t.Errorf("expect err %v, but %v", RateLimitErr, err)
+	}
+}
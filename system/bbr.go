@@ -0,0 +1,246 @@
+package system
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/lanceryou/defender"
+	"github.com/lanceryou/defender/internal/base"
+	"github.com/lanceryou/defender/pkg/timering"
+)
+
+var (
+	SystemOverloadErr = errors.New("system overload")
+)
+
+// 触发丢弃后的冷却时间，期间即使 CPU 回落也继续按 BBR 规则判断，避免抖动
+const coolingDown = time.Second
+
+// BBR 系统自适应保护（系统保护）
+// 参考 TCP BBR：系统最大吞吐 = 最大通过 QPS * 最小耗时
+// CPU 超过阈值且正在处理的请求数超过 maxPass * minRT 时拒绝入口流量
+type BBR struct {
+	opt          Options
+	bucketPerSec float64
+	inflight     int64
+	prevDropTime int64 // 纳秒，0 表示未发生过丢弃
+	stat         *bbrStat
+}
+
+func NewBBR(opts ...Option) *BBR {
+	opt := Options{
+		cpuThreshold: 800,
+		cpuUsage:     CPUUsage,
+		windowMs:     5000,
+		bucketCount:  50,
+		priority:     defaultPriority,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &BBR{
+		opt:          opt,
+		bucketPerSec: 1000 / float64(opt.windowMs/opt.bucketCount),
+		stat:         newBBRStat(opt.windowMs, opt.bucketCount),
+	}
+}
+
+// Register 创建 BBR 并注册到全局治理链
+// 需要通过 defender.Enter 并带上 defender.WithTrafficType(defender.Inbound) 使用，defender.Check 不生效
+func Register(opts ...Option) *BBR {
+	b := NewBBR(opts...)
+	defender.Register(b)
+	return b
+}
+
+// Stat 当前统计
+type Stat struct {
+	CPU         int64
+	InFlight    int64
+	MaxInFlight int64
+	MaxPass     int64
+	MinRT       int64 // 毫秒
+}
+
+func (b *BBR) Stat() Stat {
+	now := base.UnixMs(time.Now())
+	maxPass := b.stat.maxPass(now)
+	minRT := b.stat.minRT(now)
+	return Stat{
+		CPU:         b.opt.cpuUsage(),
+		InFlight:    atomic.LoadInt64(&b.inflight),
+		MaxInFlight: b.maxInFlight(maxPass, minRT),
+		MaxPass:     maxPass,
+		MinRT:       minRT,
+	}
+}
+
+// Pressure 实现 priority.PressureSource
+// BBR 在 CPU 与并发同时超限时才丢弃，取 CPU 使用率与阈值之比、并发与 maxPass*minRT 之比中较小的一个
+func (b *BBR) Pressure() float64 {
+	if b.opt.cpuThreshold <= 0 {
+		return 0
+	}
+	cpu := float64(b.opt.cpuUsage()) / float64(b.opt.cpuThreshold)
+
+	now := base.UnixMs(time.Now())
+	maxInFlight := b.maxInFlight(b.stat.maxPass(now), b.stat.minRT(now))
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	inflight := float64(atomic.LoadInt64(&b.inflight)) / float64(maxInFlight)
+	return math.Min(cpu, inflight)
+}
+
+// maxInFlight = 每秒最大通过数 * 最小耗时(秒)
+func (b *BBR) maxInFlight(maxPass int64, minRT int64) int64 {
+	return int64(math.Floor(float64(maxPass)*b.bucketPerSec*float64(minRT)/1000 + 0.5))
+}
+
+func (b *BBR) shouldDrop() bool {
+	now := time.Now()
+	if b.opt.cpuUsage() < b.opt.cpuThreshold {
+		prevDrop := atomic.LoadInt64(&b.prevDropTime)
+		if prevDrop == 0 || now.UnixNano()-prevDrop > int64(coolingDown) {
+			if prevDrop != 0 {
+				atomic.CompareAndSwapInt64(&b.prevDropTime, prevDrop, 0)
+			}
+			return false
+		}
+	}
+
+	inflight := atomic.LoadInt64(&b.inflight)
+	nowMs := base.UnixMs(now)
+	drop := inflight > 1 && inflight > b.maxInFlight(b.stat.maxPass(nowMs), b.stat.minRT(nowMs))
+	if drop {
+		atomic.StoreInt64(&b.prevDropTime, now.UnixNano())
+	}
+	return drop
+}
+
+// Check 实现 defender.Defender，不做任何判断
+// Check 既不知道流量方向，也没有调用结束通知，无法统计并发、通过数与耗时，BBR 只通过 CheckEntry 生效
+func (b *BBR) Check(resource string) error {
+	return nil
+}
+
+// CheckEntry 实现 defender.EntryChecker，只保护入口流量
+func (b *BBR) CheckEntry(e *defender.Entry) error {
+	if e.TrafficType() != defender.Inbound {
+		return nil
+	}
+	if b.shouldDrop() {
+		return b.blockError(e.Resource())
+	}
+	atomic.AddInt64(&b.inflight, 1)
+	return nil
+}
+
+// Exit 实现 defender.ExitHandler，记录通过数与耗时
+func (b *BBR) Exit(e *defender.Entry, err error) {
+	if e.TrafficType() != defender.Inbound {
+		return
+	}
+	atomic.AddInt64(&b.inflight, -1)
+	if e.BlockError() != nil {
+		return
+	}
+	b.stat.add(base.UnixMs(time.Now()), e.RT().Milliseconds())
+}
+
+func (b *BBR) String() string {
+	return "system:bbr"
+}
+
+// Priority 实现 defender.Prioritized
+func (b *BBR) Priority() int {
+	return b.opt.priority
+}
+
+func (b *BBR) blockError(resource string) error {
+	return &defender.BlockError{
+		Resource:   resource,
+		Kind:       "system",
+		RuleID:     "bbr",
+		RetryAfter: coolingDown,
+		Err:        SystemOverloadErr,
+	}
+}
+
+// bbrStat 按 bucket 统计通过数与耗时
+// 计算 maxPass 与 minRT 时跳过当前 bucket，当前 bucket 数据不完整
+type bbrStat struct {
+	buckets []bbrBucket
+	*timering.TimeRing
+}
+
+func newBBRStat(intervalInMs uint32, bucketCount uint32) *bbrStat {
+	s := &bbrStat{
+		buckets: make([]bbrBucket, bucketCount),
+	}
+
+	bucketResetArray := make([]timering.ResetBucket, len(s.buckets))
+	for i := 0; i < len(bucketResetArray); i++ {
+		bucketResetArray[i] = &s.buckets[i]
+	}
+	s.TimeRing = timering.NewTimeRing(intervalInMs, bucketCount)
+	s.SetResetBuckets(bucketResetArray)
+	return s
+}
+
+func (s *bbrStat) add(nowMs int64, rt int64) {
+	idx := s.CurrentIndex(nowMs)
+	atomic.AddInt64(&s.buckets[idx].pass, 1)
+	atomic.AddInt64(&s.buckets[idx].rt, rt)
+}
+
+// maxPass 单个 bucket 的最大通过数
+func (s *bbrStat) maxPass(nowMs int64) int64 {
+	cur := s.CurrentIndex(nowMs)
+	var max int64 = 1
+	for i := range s.buckets {
+		if int64(i) == cur || !s.IsValid(int64(i), nowMs) {
+			continue
+		}
+		if pass := atomic.LoadInt64(&s.buckets[i].pass); pass > max {
+			max = pass
+		}
+	}
+	return max
+}
+
+// minRT 单个 bucket 的最小平均耗时，毫秒
+func (s *bbrStat) minRT(nowMs int64) int64 {
+	cur := s.CurrentIndex(nowMs)
+	var min float64 = math.MaxFloat64
+	for i := range s.buckets {
+		if int64(i) == cur || !s.IsValid(int64(i), nowMs) {
+			continue
+		}
+		pass := atomic.LoadInt64(&s.buckets[i].pass)
+		if pass == 0 {
+			continue
+		}
+		if avg := float64(atomic.LoadInt64(&s.buckets[i].rt)) / float64(pass); avg < min {
+			min = avg
+		}
+	}
+	// 耗时按毫秒记录，亚毫秒的调用记为 0，至少按 1ms 计算，否则 maxInFlight 恒为 0
+	if min == math.MaxFloat64 || min < 1 {
+		return 1
+	}
+	return int64(math.Ceil(min))
+}
+
+type bbrBucket struct {
+	pass int64 // 通过数
+	rt   int64 // 耗时总和，毫秒
+}
+
+func (b *bbrBucket) Reset() {
+	atomic.StoreInt64(&b.pass, 0)
+	atomic.StoreInt64(&b.rt, 0)
+}
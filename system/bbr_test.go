@@ -0,0 +1,106 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lanceryou/defender"
+)
+
+func TestBBR_Drop(t *testing.T) {
+	var cpu int64 = 900
+	g := defender.NewGroup()
+	b := NewBBR(
+		WithCPUThreshold(800),
+		WithWindow(1000, 10),
+		WithCPUUsage(func() int64 {
+			return atomic.LoadInt64(&cpu)
+		}),
+	)
+	g.Register(b)
+	if p := b.Pressure(); p != 0 {
+		t.Errorf("expect pressure 0 without inflight, but %v", p)
+	}
+
+	// 没有历史统计时 maxInFlight 为 0，只允许 1 个以上的请求在途
+	var entries []*defender.Entry
+	for i := 0; i < 2; i++ {
+		e, err := g.Enter(context.Background(), "res", defender.WithTrafficType(defender.Inbound))
+		if err != nil {
+			t.Fatalf("unexpect err %v", err)
+		}
+		entries = append(entries, e)
+	}
+	_, err := g.Enter(context.Background(), "res", defender.WithTrafficType(defender.Inbound))
+	if !errors.Is(err, SystemOverloadErr) {
+		t.Errorf("expect err %v, but %v", SystemOverloadErr, err)
+	}
+	if p := b.Pressure(); p < 1 {
+		t.Errorf("expect pressure >= 1, but %v", p)
+	}
+	// Check 没有流量方向与调用结束通知，不生效
+	if err = g.Check("res"); err != nil {
+		t.Errorf("expect check pass, but %v", err)
+	}
+
+	// 出口流量不受影响
+	e, err := g.Enter(context.Background(), "res")
+	if err != nil {
+		t.Fatalf("outbound should pass, but %v", err)
+	}
+	e.Exit(nil)
+
+	for _, e := range entries {
+		e.Exit(nil)
+	}
+	e, err = g.Enter(context.Background(), "res", defender.WithTrafficType(defender.Inbound))
+	if err != nil {
+		t.Errorf("expect pass after exit, but %v", err)
+	}
+	e.Exit(nil)
+}
+
+func TestBBR_SubMillisecondRT(t *testing.T) {
+	var cpu int64
+	g := defender.NewGroup()
+	b := NewBBR(
+		WithCPUThreshold(800),
+		WithWindow(1000, 10),
+		WithCPUUsage(func() int64 {
+			return atomic.LoadInt64(&cpu)
+		}),
+	)
+	g.Register(b)
+
+	// 快速调用耗时不足 1ms
+	for i := 0; i < 1000; i++ {
+		e, err := g.Enter(context.Background(), "res", defender.WithTrafficType(defender.Inbound))
+		if err != nil {
+			t.Fatalf("unexpect err %v", err)
+		}
+		e.Exit(nil)
+	}
+	// 等当前 bucket 结束后参与统计
+	time.Sleep(150 * time.Millisecond)
+
+	st := b.Stat()
+	if st.MinRT != 1 || st.MaxInFlight < 2 {
+		t.Fatalf("expect min rt 1 and max inflight >= 2, but %+v", st)
+	}
+
+	atomic.StoreInt64(&cpu, 900)
+	var entries []*defender.Entry
+	for i := int64(0); i < st.MaxInFlight; i++ {
+		e, err := g.Enter(context.Background(), "res", defender.WithTrafficType(defender.Inbound))
+		if err != nil {
+			t.Fatalf("expect %v inflight pass, but %v at %v", st.MaxInFlight, err, i)
+		}
+		entries = append(entries, e)
+	}
+	for _, e := range entries {
+		e.Exit(nil)
+	}
+}
@@ -0,0 +1,11 @@
+package system
+
+import (
+	"github.com/lanceryou/defender/pkg/sampler"
+)
+
+// CPUUsage CPU 使用率，千分比
+// 容器内相对 cgroup 配额计算，首次调用时启动全局采样
+func CPUUsage() int64 {
+	return sampler.Default().Stats().CPU
+}
@@ -0,0 +1,184 @@
+package system
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lanceryou/defender"
+)
+
+// MetricType 过载保护的指标
+type MetricType int32
+
+const (
+	// HeapInUse 堆内存占用，字节
+	HeapInUse MetricType = iota
+	// Goroutines 协程数
+	Goroutines
+)
+
+func (m MetricType) String() string {
+	switch m {
+	case HeapInUse:
+		return "HeapInUse"
+	case Goroutines:
+		return "Goroutines"
+	default:
+		return fmt.Sprintf("MetricType(%d)", int32(m))
+	}
+}
+
+// Rule 过载保护规则
+// 指标超过 Threshold 开始丢弃，回落到 RecoverThreshold 以下才恢复，避免在阈值附近抖动
+type Rule struct {
+	Metric    MetricType
+	Threshold uint64
+	// RecoverThreshold 恢复阈值，默认 Threshold 的 90%
+	RecoverThreshold uint64
+}
+
+func (r *Rule) recoverThreshold() uint64 {
+	if r.RecoverThreshold == 0 || r.RecoverThreshold > r.Threshold {
+		return r.Threshold / 10 * 9
+	}
+	return r.RecoverThreshold
+}
+
+// LoadShedder 内存与协程数过载保护
+type LoadShedder struct {
+	opt      Options
+	rules    []Rule
+	shedding []int32 // 每条规则是否处于丢弃状态
+
+	lastSample int64 // 纳秒
+	mu         sync.Mutex
+	heapSample []metrics.Sample
+	heapInUse  uint64
+}
+
+func NewLoadShedder(rules []Rule, opts ...Option) *LoadShedder {
+	opt := Options{
+		sampleInterval: 100 * time.Millisecond,
+		priority:       defaultPriority,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &LoadShedder{
+		opt:      opt,
+		rules:    rules,
+		shedding: make([]int32, len(rules)),
+		heapSample: []metrics.Sample{
+			{Name: "/memory/classes/heap/objects:bytes"},
+			{Name: "/memory/classes/heap/unused:bytes"},
+		},
+	}
+}
+
+// HeapInUse 最近一次采样的堆内存占用
+func (s *LoadShedder) HeapInUse() uint64 {
+	s.sampleHeap()
+	return atomic.LoadUint64(&s.heapInUse)
+}
+
+// sampleHeap 读取 runtime/metrics 有开销，每 sampleInterval 最多读取一次
+func (s *LoadShedder) sampleHeap() {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&s.lastSample)
+	if now-last < int64(s.opt.sampleInterval) || !atomic.CompareAndSwapInt64(&s.lastSample, last, now) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics.Read(s.heapSample)
+	var inuse uint64
+	for _, sample := range s.heapSample {
+		if sample.Value.Kind() == metrics.KindUint64 {
+			inuse += sample.Value.Uint64()
+		}
+	}
+	atomic.StoreUint64(&s.heapInUse, inuse)
+}
+
+func (s *LoadShedder) value(m MetricType) uint64 {
+	switch m {
+	case HeapInUse:
+		return s.HeapInUse()
+	case Goroutines:
+		return uint64(runtime.NumGoroutine())
+	default:
+		return 0
+	}
+}
+
+// Pressure 实现 priority.PressureSource，各规则指标与阈值之比的最大值
+func (s *LoadShedder) Pressure() float64 {
+	var max float64
+	for _, r := range s.rules {
+		if r.Threshold == 0 {
+			continue
+		}
+		if p := float64(s.value(r.Metric)) / float64(r.Threshold); p > max {
+			max = p
+		}
+	}
+	return max
+}
+
+// shouldDrop 返回命中的规则
+func (s *LoadShedder) shouldDrop() (Rule, bool) {
+	for i, r := range s.rules {
+		v := s.value(r.Metric)
+		if atomic.LoadInt32(&s.shedding[i]) == 1 {
+			if v >= r.recoverThreshold() {
+				return r, true
+			}
+			atomic.StoreInt32(&s.shedding[i], 0)
+		} else if v >= r.Threshold {
+			atomic.StoreInt32(&s.shedding[i], 1)
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Check 实现 defender.Defender
+func (s *LoadShedder) Check(resource string) error {
+	if r, drop := s.shouldDrop(); drop {
+		return s.blockError(resource, r)
+	}
+	return nil
+}
+
+// CheckEntry 实现 defender.EntryChecker，只保护入口流量
+func (s *LoadShedder) CheckEntry(e *defender.Entry) error {
+	if e.TrafficType() != defender.Inbound {
+		return nil
+	}
+	return s.Check(e.Resource())
+}
+
+func (s *LoadShedder) String() string {
+	return "system:load"
+}
+
+// Priority 实现 defender.Prioritized
+func (s *LoadShedder) Priority() int {
+	return s.opt.priority
+}
+
+func (s *LoadShedder) blockError(resource string, r Rule) error {
+	return &defender.BlockError{
+		Resource:   resource,
+		Kind:       "system",
+		RuleID:     r.Metric.String(),
+		RetryAfter: s.opt.sampleInterval,
+		Err:        SystemOverloadErr,
+	}
+}
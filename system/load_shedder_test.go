@@ -0,0 +1,82 @@
+package system
+
+import (
+	"errors"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadShedder_Hysteresis(t *testing.T) {
+	base := uint64(runtime.NumGoroutine())
+	s := NewLoadShedder([]Rule{
+		{Metric: Goroutines, Threshold: base + 10, RecoverThreshold: base + 5},
+	})
+
+	var wgs [2]sync.WaitGroup
+	stops := [2]chan struct{}{make(chan struct{}), make(chan struct{})}
+	spawn := func(i, n int) {
+		for j := 0; j < n; j++ {
+			wgs[i].Add(1)
+			go func() {
+				defer wgs[i].Done()
+				<-stops[i]
+			}()
+		}
+	}
+	stop := func(i, remain int) {
+		close(stops[i])
+		wgs[i].Wait()
+		// 等待协程真正退出
+		for j := 0; j < 100 && runtime.NumGoroutine() > int(base)+remain; j++ {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	spawn(0, 3)
+	spawn(1, 7)
+	if err := s.Check("res"); err == nil {
+		t.Fatalf("expect drop when goroutines over threshold")
+	}
+
+	// 回落到阈值以下但仍高于恢复阈值，继续丢弃
+	stop(0, 7)
+	if err := s.Check("res"); err == nil {
+		t.Errorf("expect drop until recover threshold")
+	}
+
+	stop(1, 0)
+	if err := s.Check("res"); err != nil {
+		t.Errorf("expect pass after recover, but %v", err)
+	}
+}
+
+func TestLoadShedder_HeapInUse(t *testing.T) {
+	debug.FreeOSMemory()
+	s := NewLoadShedder(nil, WithSampleInterval(0))
+	base := s.HeapInUse()
+	if base == 0 {
+		t.Fatalf("expect heap in use > 0")
+	}
+	s = NewLoadShedder([]Rule{
+		{Metric: HeapInUse, Threshold: base + 32<<20, RecoverThreshold: base + 16<<20},
+	}, WithSampleInterval(0))
+
+	buf := make([]byte, 64<<20)
+	if err := s.Check("res"); !errors.Is(err, SystemOverloadErr) {
+		t.Errorf("expect drop when heap over threshold, but %v", err)
+	}
+	if p := s.Pressure(); p < 1 {
+		t.Errorf("expect pressure >= 1, but %v", p)
+	}
+	runtime.KeepAlive(buf)
+
+	// 释放后堆内存回落到恢复阈值以下
+	buf = nil
+	debug.FreeOSMemory()
+	if err := s.Check("res"); err != nil {
+		t.Errorf("expect pass after recover, but %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package system
+
+import (
+	"time"
+)
+
+// 系统保护默认排在治理链最前面
+const defaultPriority = 50
+
+type Options struct {
+	cpuThreshold   int64
+	cpuUsage       func() int64
+	windowMs       uint32
+	bucketCount    uint32
+	sampleInterval time.Duration
+	priority       int
+}
+
+type Option func(*Options)
+
+// WithCPUThreshold CPU 使用率阈值，千分比，默认 800
+func WithCPUThreshold(threshold int64) Option {
+	return func(o *Options) {
+		o.cpuThreshold = threshold
+	}
+}
+
+// WithCPUUsage 自定义 CPU 使用率来源，千分比，默认使用 sampler 的 cgroup 感知采样
+func WithCPUUsage(fn func() int64) Option {
+	return func(o *Options) {
+		o.cpuUsage = fn
+	}
+}
+
+// WithWindow 统计窗口，默认 5s 50 个 bucket
+func WithWindow(windowMs uint32, bucketCount uint32) Option {
+	return func(o *Options) {
+		o.windowMs = windowMs
+		o.bucketCount = bucketCount
+	}
+}
+
+// WithSampleInterval 堆内存采样间隔，默认 100ms
+func WithSampleInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.sampleInterval = interval
+	}
+}
+
+// WithPriority 在治理链中的优先级
+func WithPriority(priority int) Option {
+	return func(o *Options) {
+		o.priority = priority
+	}
+}